@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/core/doctor"
+)
+
+func loadConfigurationForCommand(options configuration.LoadOptions) *configuration.Runtime {
+	config, _, err := configuration.LoadWithOptions(options)
+	if config == nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %s\n", err.Error())
+		os.Exit(1)
+	}
+	return config
+}
+
+// runConfigValidate reports every configuration issue and exits non-zero if any was found, warnings do not fail
+func runConfigValidate(options configuration.LoadOptions) {
+	config := loadConfigurationForCommand(options)
+
+	for _, warning := range config.ValidationWarnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning.Error())
+	}
+	issues := config.ValidateAll()
+	if len(issues) == 0 {
+		fmt.Println("configuration is valid")
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "%s\n", issue.Error())
+	}
+	fmt.Fprintf(os.Stderr, "configuration is invalid - %d issue(s) found\n", len(issues))
+	os.Exit(1)
+}
+
+// runDoctor checks the environment described by the configuration and exits non-zero if any check failed
+func runDoctor(options configuration.LoadOptions) {
+	config := loadConfigurationForCommand(options)
+
+	failed := 0
+	for _, warning := range config.ValidationWarnings() {
+		fmt.Printf("[%s] configuration: %s\n", doctor.CheckWarning, warning.Error())
+	}
+	for _, issue := range config.ValidateAll() {
+		fmt.Printf("[%s] configuration: %s\n", doctor.CheckFailed, issue.Error())
+		failed++
+	}
+
+	for _, result := range doctor.Run(context.Background(), config) {
+		fmt.Printf("[%s] %s: %s\n", result.Status, result.Name, result.Message)
+		if result.Status == doctor.CheckFailed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+}
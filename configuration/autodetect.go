@@ -1,15 +1,21 @@
 package configuration
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/hjson/hjson-go/v4"
+	"github.com/samber/lo"
 	"github.com/tez-capital/tezbake/ami"
 	"github.com/tez-capital/tezpay/configuration"
 	"github.com/tez-capital/tezpeak/constants"
@@ -18,6 +24,182 @@ import (
 	"github.com/tez-capital/tezpay/state"
 )
 
+type AmiAppKind string
+
+const (
+	NodeAmiApp    AmiAppKind = "node"
+	SignerAmiApp  AmiAppKind = "signer"
+	TezpayAmiApp  AmiAppKind = "tezpay"
+	DalNodeAmiApp AmiAppKind = "dal"
+	AccuserAmiApp AmiAppKind = "accuser"
+)
+
+type DetectedAmiApp struct {
+	// directory name relative to the root dir
+	Name string
+	Path string
+	// ami app type id, e.g. xtz.node
+	Type string
+	Kind AmiAppKind
+}
+
+type AutoDetectOptions struct {
+	// asks to confirm every detected app, baker and node before it is written
+	Interactive bool
+	Input       io.Reader
+	Output      io.Writer
+}
+
+type autoDetectConfirmer struct {
+	interactive bool
+	reader      *bufio.Reader
+	output      io.Writer
+}
+
+func newAutoDetectConfirmer(options AutoDetectOptions) *autoDetectConfirmer {
+	confirmer := &autoDetectConfirmer{interactive: options.Interactive, output: options.Output}
+	if options.Input != nil {
+		confirmer.reader = bufio.NewReader(options.Input)
+	}
+	if confirmer.output == nil {
+		confirmer.output = os.Stdout
+	}
+	return confirmer
+}
+
+// confirm returns true if not interactive, otherwise asks until yes or no is answered.
+// Empty answer means yes, closed input means no.
+func (c *autoDetectConfirmer) confirm(format string, args ...any) bool {
+	if !c.interactive {
+		return true
+	}
+	if c.reader == nil {
+		return false
+	}
+
+	for {
+		fmt.Fprintf(c.output, format+" [Y/n]: ", args...)
+		line, err := c.reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case answer == "y" || answer == "yes":
+			return true
+		case answer == "n" || answer == "no":
+			return false
+		case err != nil:
+			fmt.Fprintln(c.output)
+			return false
+		case answer == "":
+			return true
+		}
+	}
+}
+
+/*
+	{
+		"type": "xtz.node" | { "id": "xtz.node", "version": "latest" }
+		"configuration": {
+				...
+		},
+	}
+*/
+type amiAppDefinition struct {
+	Type          json.RawMessage `json:"type,omitempty"`
+	Configuration json.RawMessage `json:"configuration,omitempty"`
+}
+
+func (d *amiAppDefinition) TypeId() string {
+	var id string
+	if err := json.Unmarshal(d.Type, &id); err == nil {
+		return id
+	}
+	var typeInfo struct {
+		Id string `json:"id"`
+	}
+	if err := json.Unmarshal(d.Type, &typeInfo); err == nil {
+		return typeInfo.Id
+	}
+	return ""
+}
+
+func readAmiAppDefinition(appPath string) (*amiAppDefinition, error) {
+	data, err := os.ReadFile(path.Join(appPath, "app.json"))
+	if err != nil {
+		data, err = os.ReadFile(path.Join(appPath, "app.hjson"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var definition amiAppDefinition
+	if err := hjson.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// classifyAmiApp maps ami app type (e.g. xtz.node, xtz.dal-node, xtz.tezpay) to the kind tezpeak cares about
+func classifyAmiApp(appType string) (AmiAppKind, bool) {
+	appType = strings.ToLower(appType)
+	switch {
+	case strings.Contains(appType, "dal"):
+		return DalNodeAmiApp, true
+	case strings.Contains(appType, "accuser"):
+		return AccuserAmiApp, true
+	case strings.Contains(appType, "signer"):
+		return SignerAmiApp, true
+	case strings.Contains(appType, "tezpay"), strings.HasSuffix(appType, ".pay"):
+		return TezpayAmiApp, true
+	case strings.Contains(appType, "node"):
+		return NodeAmiApp, true
+	default:
+		return "", false
+	}
+}
+
+// ScanAmiApps looks for installed ami apps directly under the root dir and classifies them by their ami type
+func ScanAmiApps(rootDir string) ([]DetectedAmiApp, error) {
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := []DetectedAmiApp{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		appPath := path.Join(rootDir, entry.Name())
+		if !ami.IsAppInstalled(appPath) {
+			continue
+		}
+
+		definition, err := readAmiAppDefinition(appPath)
+		if err != nil {
+			slog.Debug("Failed to read ami app definition, skipping", "path", appPath, "error", err.Error())
+			continue
+		}
+
+		appType := definition.TypeId()
+		kind, ok := classifyAmiApp(appType)
+		if !ok {
+			slog.Debug("Unsupported ami app, skipping", "path", appPath, "type", appType)
+			continue
+		}
+		apps = append(apps, DetectedAmiApp{
+			Name: entry.Name(),
+			Path: appPath,
+			Type: appType,
+			Kind: kind,
+		})
+	}
+	return apps, nil
+}
+
+func filterAmiApps(apps []DetectedAmiApp, kind AmiAppKind) []DetectedAmiApp {
+	return lo.Filter(apps, func(app DetectedAmiApp, _ int) bool { return app.Kind == kind })
+}
+
 /*
 	tezpay: {
 		applications: {
@@ -30,17 +212,8 @@ import (
 		}
 	}
 */
-func autoDetectTezpayConfiguration(rootDir string) (json.RawMessage, error) {
-	if rootDir == "" {
-		return nil, errors.New("rootDir is empty")
-	}
-
-	workingDir := path.Join(rootDir, constants.DEFAULT_TEZPAY_APP_PATH)
-	if !ami.IsAppInstalled(workingDir) {
-		return nil, errors.New("tezpay not found, skipping")
-	}
-
-	state.Init(workingDir, state.StateInitOptions{})
+func autoDetectTezpayConfiguration(app DetectedAmiApp) (json.RawMessage, error) {
+	state.Init(app.Path, state.StateInitOptions{})
 
 	config, err := configuration.Load()
 	if err != nil {
@@ -61,7 +234,7 @@ func autoDetectTezpayConfiguration(rootDir string) (json.RawMessage, error) {
 	tezpayModuleConfiguration := &TezpayModuleConfiguration{
 		moduleConfigurationbase: moduleConfigurationbase{
 			Applications: map[string]string{
-				"tezpay": constants.DEFAULT_TEZPAY_APP_PATH,
+				"tezpay": app.Name,
 			},
 		},
 		PayoutWallet: pkh.String(),
@@ -77,87 +250,256 @@ func autoDetectTezpayConfiguration(rootDir string) (json.RawMessage, error) {
 	{
 		"configuration": {
 				...
+				"NODE_TYPE": "baker",
+				"RPC_ADDR": "127.0.0.1:8732",
+				"STARTUP_ARGS": [ "--network=ghostnet" ],
 				"additional_key_aliases": [ "key" ]
 		},
 	}
 */
 type nodeAppJsonPartialConfiguration struct {
+	NodeType             string   `json:"NODE_TYPE,omitempty"`
+	RpcAddress           string   `json:"RPC_ADDR,omitempty"`
+	StartupArgs          []string `json:"STARTUP_ARGS,omitempty"`
 	RemoteSignerUrl      string   `json:"REMOTE_SIGNER_ADDR,omitempty"`
 	AdditionalKeyAliases []string `json:"additional_key_aliases,omitempty"`
 }
 
-type nodeAppJsonPartial struct {
-	Configuration nodeAppJsonPartialConfiguration
+// octez node configuration in data/.tezos-node/config.json
+/*
+	{
+		"rpc": { "listen-addrs": [ "127.0.0.1:8732" ] },
+		"network": "ghostnet" | { "chain_name": "TEZOS_GHOSTNET_2022-01-25T15:00:00Z", ... }
+	}
+*/
+type octezNodeConfiguration struct {
+	Rpc struct {
+		ListenAddrs []string `json:"listen-addrs,omitempty"`
+	} `json:"rpc,omitempty"`
+	Network json.RawMessage `json:"network,omitempty"`
+}
+
+type detectedNode struct {
+	app        DetectedAmiApp
+	config     nodeAppJsonPartialConfiguration
+	rpcAddress string
+	network    string
+}
+
+func loadNodeAppConfiguration(app DetectedAmiApp) nodeAppJsonPartialConfiguration {
+	config := nodeAppJsonPartialConfiguration{
+		AdditionalKeyAliases: []string{},
+	}
+	definition, err := readAmiAppDefinition(app.Path)
+	if err != nil || len(definition.Configuration) == 0 {
+		return config
+	}
+	if err := hjson.Unmarshal(definition.Configuration, &config); err != nil {
+		slog.Warn("Failed to parse node app configuration", "path", app.Path, "error", err.Error())
+	}
+	return config
+}
+
+func getStartupArgValue(args []string, name string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, name+"="); ok {
+			return value
+		}
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// normalizeNetworkName maps network alias or chain name to network name, e.g. TEZOS_GHOSTNET_2022-01-25T15:00:00Z to ghostnet
+func normalizeNetworkName(network string) string {
+	network = strings.ToLower(network)
+	for _, known := range []string{constants.MAINNET_NETWORK, constants.GHOSTNET_NETWORK} {
+		if strings.Contains(network, known) {
+			return known
+		}
+	}
+	return network
+}
+
+func parseOctezNetwork(raw json.RawMessage) string {
+	var alias string
+	if err := json.Unmarshal(raw, &alias); err == nil {
+		return normalizeNetworkName(alias)
+	}
+	var custom struct {
+		ChainName string `json:"chain_name"`
+	}
+	if err := json.Unmarshal(raw, &custom); err == nil {
+		return normalizeNetworkName(custom.ChainName)
+	}
+	return ""
+}
+
+// rpcListenAddressToUrl turns node listen address (e.g. 0.0.0.0:8732, [::]:8732, 127.0.0.1) into url reachable locally
+func rpcListenAddressToUrl(address string) string {
+	address = strings.TrimPrefix(strings.TrimPrefix(address, "http://"), "https://")
+	address = strings.TrimSuffix(address, "/")
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, constants.DEFAULT_NODE_RPC_PORT
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" || host == "[::]" {
+		host = "127.0.0.1"
+	}
+	if port == "" {
+		port = constants.DEFAULT_NODE_RPC_PORT
+	}
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(host, port))
+}
+
+// detectNode reads rpc listen address and network from node app configuration,
+// startup args and the octez node configuration, in this order
+func detectNode(app DetectedAmiApp) *detectedNode {
+	node := &detectedNode{
+		app:    app,
+		config: loadNodeAppConfiguration(app),
+	}
+
+	rpcAddress := node.config.RpcAddress
+	if rpcAddress == "" {
+		rpcAddress = getStartupArgValue(node.config.StartupArgs, "--rpc-addr")
+	}
+	node.network = normalizeNetworkName(getStartupArgValue(node.config.StartupArgs, "--network"))
+
+	if data, err := os.ReadFile(path.Join(app.Path, "data", ".tezos-node", "config.json")); err == nil {
+		var octezConfig octezNodeConfiguration
+		if err := hjson.Unmarshal(data, &octezConfig); err == nil {
+			if rpcAddress == "" && len(octezConfig.Rpc.ListenAddrs) > 0 {
+				rpcAddress = octezConfig.Rpc.ListenAddrs[0]
+			}
+			if node.network == "" && len(octezConfig.Network) > 0 {
+				node.network = parseOctezNetwork(octezConfig.Network)
+			}
+		} else {
+			slog.Warn("Failed to parse octez node configuration", "path", app.Path, "error", err.Error())
+		}
+	}
+
+	if rpcAddress == "" {
+		node.rpcAddress = constants.DEFAULT_BAKER_NODE_URL + "/"
+	} else {
+		node.rpcAddress = rpcListenAddressToUrl(rpcAddress)
+	}
+	if node.network == "" {
+		node.network = constants.DEFAULT_NETWORK
+	}
+	return node
+}
+
+// selectBakerNode prefers node in the default node path, then node of baker type, then the first node found
+func selectBakerNode(nodes []DetectedAmiApp) *detectedNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	detected := lo.Map(nodes, func(app DetectedAmiApp, _ int) *detectedNode { return detectNode(app) })
+	if node, ok := lo.Find(detected, func(node *detectedNode) bool { return node.app.Name == constants.DEFAULT_NODE_APP_PATH }); ok {
+		return node
+	}
+	if node, ok := lo.Find(detected, func(node *detectedNode) bool { return strings.EqualFold(node.config.NodeType, "baker") }); ok {
+		return node
+	}
+	return detected[0]
+}
+
+func loadBakersFromNode(node *detectedNode) ([]string, error) {
+	aliases := append([]string{"baker"}, node.config.AdditionalKeyAliases...)
+
+	// read pkhs based on aliases
+	pathToPkhs := path.Join(node.app.Path, "data/.tezos-client/public_key_hashs")
+	pkhs := nodePublicKeys{}
+	fileContent := []byte{}
+	if data, err := os.ReadFile(pathToPkhs); err == nil {
+		fileContent = data
+	}
+	if err := hjson.Unmarshal(fileContent, &pkhs); err != nil {
+		return nil, errors.New("failed to read public key hashes")
+	}
+
+	bakers := []string{}
+	for _, pkh := range pkhs {
+		if slices.Contains(aliases, pkh.Name) {
+			bakers = append(bakers, pkh.Hash)
+		}
+	}
+	return bakers, nil
 }
 
 /*
 	tezbake: {
+		applications: {
+			node: node
+			signer: signer
+			dal: dal
+		}
 		bakers: [
 			tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM
 			tz1hZvgjekGo7DmQjWh7XnY5eLQD8wNYPczE
 		]
 	}
 */
-func autoDetectTezbakeConfiguration(rootDir string) (json.RawMessage, error) {
-	nodeAppPath := path.Join(rootDir, constants.DEFAULT_NODE_APP_PATH)
-	signerAppPath := path.Join(rootDir, constants.DEFAULT_SIGNER_APP_PATH)
-
+func autoDetectTezbakeConfiguration(apps []DetectedAmiApp, bakerNode *detectedNode, confirmer *autoDetectConfirmer) (json.RawMessage, error) {
 	applications := map[string]string{}
+	addApplication := func(key string, app DetectedAmiApp) {
+		if _, ok := applications[key]; ok {
+			key = app.Name
+		}
+		applications[key] = app.Name
+	}
+
 	bakers := []string{}
 	remoteSignerUrl := constants.DEFAULT_BAKER_SIGNER_URL
-	if ami.IsAppInstalled(nodeAppPath) {
-		applications["node"] = constants.DEFAULT_NODE_APP_PATH
-
-		keys := []string{"baker"}
+	if bakerNode != nil {
+		applications["node"] = bakerNode.app.Name
 
-		nodeAppConfig := nodeAppJsonPartial{
-			Configuration: nodeAppJsonPartialConfiguration{
-				AdditionalKeyAliases: []string{},
-			},
+		detectedBakers, err := loadBakersFromNode(bakerNode)
+		if err != nil {
+			return nil, err
 		}
-
-		nodeAppPath := path.Join(rootDir, constants.DEFAULT_NODE_APP_PATH, "app.json")
-		fileContent := []byte("{}")
-		if data, err := os.ReadFile(nodeAppPath); err == nil {
-			fileContent = data
-		} else {
-			nodeAppPath = path.Join(rootDir, constants.DEFAULT_NODE_APP_PATH, "app.hjson")
-			if data, err := os.ReadFile(nodeAppPath); err == nil {
-				fileContent = data
+		for _, baker := range detectedBakers {
+			if confirmer.confirm("Monitor baker %s?", baker) {
+				bakers = append(bakers, baker)
 			}
 		}
-		if err := hjson.Unmarshal(fileContent, &nodeAppConfig); err == nil {
-			keys = append(keys, nodeAppConfig.Configuration.AdditionalKeyAliases...)
-		}
 
-		if nodeAppConfig.Configuration.RemoteSignerUrl != "" {
-			remoteSignerUrl = nodeAppConfig.Configuration.RemoteSignerUrl
-		}
-
-		// read pkhs based on aliases
-		pathToPkhs := path.Join(rootDir, constants.DEFAULT_NODE_APP_PATH, "data/.tezos-client/public_key_hashs")
-		pkhs := nodePublicKeys{}
-		fileContent = []byte{}
-		if data, err := os.ReadFile(pathToPkhs); err == nil {
-			fileContent = data
-		}
-		if err := hjson.Unmarshal(fileContent, &pkhs); err != nil {
-			return nil, errors.New("failed to read public key hashes")
-		}
-		for _, pkh := range pkhs {
-			if slices.Contains(keys, pkh.Name) {
-				bakers = append(bakers, pkh.Hash)
-			}
+		if bakerNode.config.RemoteSignerUrl != "" {
+			remoteSignerUrl = bakerNode.config.RemoteSignerUrl
 		}
 	} else {
 		slog.Warn("Node app is not found, skipping")
 	}
 
-	if ami.IsAppInstalled(signerAppPath) {
-		applications["signer"] = constants.DEFAULT_SIGNER_APP_PATH
-	} else {
+	for _, app := range filterAmiApps(apps, NodeAmiApp) {
+		if bakerNode == nil || app.Name != bakerNode.app.Name {
+			addApplication(app.Name, app)
+		}
+	}
+
+	signers := filterAmiApps(apps, SignerAmiApp)
+	if len(signers) == 0 {
 		slog.Warn("Signer app is not found, skipping")
 	}
+	for _, app := range signers {
+		addApplication("signer", app)
+	}
+	for _, app := range filterAmiApps(apps, DalNodeAmiApp) {
+		addApplication("dal", app)
+	}
+	for _, app := range filterAmiApps(apps, AccuserAmiApp) {
+		addApplication("accuser", app)
+	}
+
+	if len(applications) == 0 {
+		return nil, errors.New("no node, signer, dal node or accuser app found")
+	}
 
 	tezbakeModuleConfiguration := &TezbakeModuleConfiguration{
 		moduleConfigurationbase: moduleConfigurationbase{
@@ -171,7 +513,35 @@ func autoDetectTezbakeConfiguration(rootDir string) (json.RawMessage, error) {
 	return hjson.MarshalWithOptions(tezbakeModuleConfiguration, hjson.DefaultOptions())
 }
 
-func AutoDetect(rootDir string, destinationFile string) {
+// autoDetectNodes creates baker node entry from the detected node and adds public fallbacks for its network
+func autoDetectNodes(bakerNode *detectedNode, confirmer *autoDetectConfirmer) map[string]TezosNode {
+	if bakerNode == nil {
+		return nil // defaults are used
+	}
+
+	nodes := map[string]TezosNode{}
+	node := BAKER_NODE
+	node.Address = bakerNode.rpcAddress
+	if confirmer.confirm("Use baker node at %s (%s)?", node.Address, bakerNode.network) {
+		nodes["baker"] = node
+	}
+
+	publicNodes := getPublicNodes(bakerNode.network)
+	if len(publicNodes) == 0 {
+		slog.Warn("No public nodes known for the network, add fallback nodes manually", "network", bakerNode.network)
+	}
+	ids := lo.Keys(publicNodes)
+	slices.Sort(ids)
+	for _, id := range ids {
+		if confirmer.confirm("Use public node %s at %s as fallback?", id, publicNodes[id].Address) {
+			nodes[id] = publicNodes[id]
+		}
+	}
+	return nodes
+}
+
+func AutoDetect(rootDir string, destinationFile string, options AutoDetectOptions) {
+	confirmer := newAutoDetectConfirmer(options)
 	modules := map[string]json.RawMessage{}
 	absRootDir, err := filepath.Abs(rootDir)
 	if err != nil {
@@ -180,26 +550,48 @@ func AutoDetect(rootDir string, destinationFile string) {
 		rootDir = absRootDir
 	}
 
-	tezpayConfig, err := autoDetectTezpayConfiguration(rootDir)
+	detectedApps, err := ScanAmiApps(rootDir)
 	if err != nil {
-		slog.Warn("Failed to auto-detect tezpay configuration", "error", err.Error())
+		slog.Error("Failed to scan root dir for ami apps", "error", err.Error())
+		return
+	}
+	apps := []DetectedAmiApp{}
+	for _, app := range detectedApps {
+		slog.Info("Detected ami app", "name", app.Name, "type", app.Type, "kind", app.Kind)
+		if confirmer.confirm("Use %s app %s (%s)?", app.Kind, app.Name, app.Type) {
+			apps = append(apps, app)
+		}
+	}
+
+	tezpayApps := filterAmiApps(apps, TezpayAmiApp)
+	if len(tezpayApps) == 0 {
+		slog.Warn("Failed to auto-detect tezpay configuration", "error", "tezpay not found, skipping")
 	} else {
-		modules[constants.TEZPAY_MODULE_ID] = tezpayConfig
+		if len(tezpayApps) > 1 {
+			slog.Warn("Multiple tezpay apps found, only the first one is used", "app", tezpayApps[0].Name)
+		}
+		tezpayConfig, err := autoDetectTezpayConfiguration(tezpayApps[0])
+		if err != nil {
+			slog.Warn("Failed to auto-detect tezpay configuration", "error", err.Error())
+		} else {
+			modules[constants.TEZPAY_MODULE_ID] = tezpayConfig
+		}
 	}
 
-	tezbakeConfig, err := autoDetectTezbakeConfiguration(rootDir)
+	bakerNode := selectBakerNode(filterAmiApps(apps, NodeAmiApp))
+	tezbakeConfig, err := autoDetectTezbakeConfiguration(apps, bakerNode, confirmer)
 	if err != nil {
 		slog.Warn("Failed to auto-detect tezbake configuration", "error", err.Error())
 	} else {
 		modules[constants.TEZBAKE_MODULE_ID] = tezbakeConfig
 	}
 
-	config := Runtime{
-		Id:      "",
+	config := v0{
 		AppRoot: rootDir,
 		Listen:  constants.DEFAULT_LISTEN_ADDRESS,
 		Modules: modules,
 		Mode:    AutoPeakMode,
+		Nodes:   autoDetectNodes(bakerNode, confirmer),
 	}
 
 	if data, err := hjson.MarshalWithOptions(config, hjson.DefaultOptions()); err == nil {
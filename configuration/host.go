@@ -0,0 +1,89 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+// HostPreferences are thresholds of host resources, usage is in percent, load is 1 minute load per cpu
+type HostPreferences struct {
+	DiskWarningThreshold        float64 `json:"disk_warning_threshold,omitempty"`
+	DiskErrorThreshold          float64 `json:"disk_error_threshold,omitempty"`
+	InodeWarningThreshold       float64 `json:"inode_warning_threshold,omitempty"`
+	InodeErrorThreshold         float64 `json:"inode_error_threshold,omitempty"`
+	MemoryWarningThreshold      float64 `json:"memory_warning_threshold,omitempty"`
+	MemoryErrorThreshold        float64 `json:"memory_error_threshold,omitempty"`
+	SwapWarningThreshold        float64 `json:"swap_warning_threshold,omitempty"`
+	SwapErrorThreshold          float64 `json:"swap_error_threshold,omitempty"`
+	LoadWarningThreshold        float64 `json:"load_warning_threshold,omitempty"`
+	LoadErrorThreshold          float64 `json:"load_error_threshold,omitempty"`
+	ClockOffsetWarningThreshold float64 `json:"clock_offset_warning_threshold,omitempty"` // milliseconds
+	ClockOffsetErrorThreshold   float64 `json:"clock_offset_error_threshold,omitempty"`   // milliseconds
+}
+
+type HostModuleConfiguration struct {
+	moduleConfigurationbase
+
+	Preferences HostPreferences `json:"preferences,omitempty"`
+}
+
+func getDefaultHostModuleConfiguration() *HostModuleConfiguration {
+	return &HostModuleConfiguration{
+		moduleConfigurationbase: moduleConfigurationbase{
+			Applications: map[string]string{
+				"node":   constants.DEFAULT_NODE_APP_PATH,
+				"signer": constants.DEFAULT_SIGNER_APP_PATH,
+			},
+		},
+	}
+}
+
+func withDefault(value *float64, defaultValue float64) {
+	if *value <= 0 {
+		*value = defaultValue
+	}
+}
+
+func (c *HostModuleConfiguration) Hydrate() {
+	p := &c.Preferences
+	withDefault(&p.DiskWarningThreshold, constants.DEFAULT_HOST_DISK_WARNING_THRESHOLD)
+	withDefault(&p.DiskErrorThreshold, constants.DEFAULT_HOST_DISK_ERROR_THRESHOLD)
+	withDefault(&p.InodeWarningThreshold, constants.DEFAULT_HOST_DISK_WARNING_THRESHOLD)
+	withDefault(&p.InodeErrorThreshold, constants.DEFAULT_HOST_DISK_ERROR_THRESHOLD)
+	withDefault(&p.MemoryWarningThreshold, constants.DEFAULT_HOST_MEMORY_WARNING_THRESHOLD)
+	withDefault(&p.MemoryErrorThreshold, constants.DEFAULT_HOST_MEMORY_ERROR_THRESHOLD)
+	withDefault(&p.SwapWarningThreshold, constants.DEFAULT_HOST_SWAP_WARNING_THRESHOLD)
+	withDefault(&p.SwapErrorThreshold, constants.DEFAULT_HOST_SWAP_ERROR_THRESHOLD)
+	withDefault(&p.LoadWarningThreshold, constants.DEFAULT_HOST_LOAD_WARNING_THRESHOLD)
+	withDefault(&p.LoadErrorThreshold, constants.DEFAULT_HOST_LOAD_ERROR_THRESHOLD)
+	withDefault(&p.ClockOffsetWarningThreshold, constants.DEFAULT_HOST_CLOCK_OFFSET_WARNING_THRESHOLD)
+	withDefault(&p.ClockOffsetErrorThreshold, constants.DEFAULT_HOST_CLOCK_OFFSET_ERROR_THRESHOLD)
+}
+
+func (c *HostModuleConfiguration) Validate() error {
+	errs := []error{}
+	p := c.Preferences
+	thresholds := []struct {
+		key            string
+		warning, error float64
+	}{
+		{"disk", p.DiskWarningThreshold, p.DiskErrorThreshold},
+		{"inode", p.InodeWarningThreshold, p.InodeErrorThreshold},
+		{"memory", p.MemoryWarningThreshold, p.MemoryErrorThreshold},
+		{"swap", p.SwapWarningThreshold, p.SwapErrorThreshold},
+		{"load", p.LoadWarningThreshold, p.LoadErrorThreshold},
+		{"clock_offset", p.ClockOffsetWarningThreshold, p.ClockOffsetErrorThreshold},
+	}
+	for _, threshold := range thresholds {
+		if threshold.warning > threshold.error {
+			errs = append(errs, ValidationIssue{
+				Key: fmt.Sprintf("preferences.%s_warning_threshold", threshold.key),
+				Err: fmt.Errorf("%w (%g > %g)", constants.ErrInvalidThresholds, threshold.warning, threshold.error),
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
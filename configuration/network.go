@@ -0,0 +1,41 @@
+package configuration
+
+import (
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+var (
+	networkChainIds = map[string]string{
+		constants.MAINNET_NETWORK:  constants.MAINNET_CHAIN_ID,
+		constants.GHOSTNET_NETWORK: constants.GHOSTNET_CHAIN_ID,
+	}
+)
+
+// GetNetworkChainId returns chain id of a known network
+func GetNetworkChainId(network string) (string, bool) {
+	chainId, ok := networkChainIds[network]
+	return chainId, ok
+}
+
+// GetNetworkByChainId returns name of a known network with the chain id
+func GetNetworkByChainId(chainId string) (string, bool) {
+	for network, id := range networkChainIds {
+		if id == chainId {
+			return network, true
+		}
+	}
+	return "", false
+}
+
+// UsesDefaultNodes reports whether nodes were not configured and follow the network
+func (r *Runtime) UsesDefaultNodes() bool {
+	return r.defaultNodes
+}
+
+// SetNetwork sets detected network and replaces default nodes with the ones for the network
+func (r *Runtime) SetNetwork(network string) {
+	r.Network = network
+	if r.defaultNodes {
+		r.Nodes = DefaultNodes(network)
+	}
+}
@@ -0,0 +1,59 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hjson/hjson-go/v4"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+func (n *TezosNode) Validate() error {
+	if !isValidHttpUrl(n.Address) {
+		return fmt.Errorf("%w %q", constants.ErrInvalidNodeUrl, n.Address)
+	}
+	return nil
+}
+
+var (
+	// serializes writes of the configuration file from node endpoints
+	saveNodesMtx sync.Mutex
+)
+
+// SaveNodes writes the nodes into the configuration file, other keys and comments are kept
+func (r *Runtime) SaveNodes(nodes map[string]TezosNode) error {
+	if r.configFile == "" {
+		return constants.ErrNoConfigurationFile
+	}
+	saveNodesMtx.Lock()
+	defer saveNodesMtx.Unlock()
+
+	var config hjson.Node
+	data, err := os.ReadFile(r.configFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		data = []byte("{}")
+	case err != nil:
+		return err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		data = []byte("{}")
+	}
+	if err := hjson.Unmarshal(data, &config); err != nil {
+		return errors.Join(constants.ErrInvalidConfig, err)
+	}
+
+	if _, _, err := config.SetKey("nodes", nodes); err != nil {
+		return err
+	}
+	r.defaultNodes = false
+
+	data, err = hjson.MarshalWithOptions(&config, hjson.DefaultOptions())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.configFile, data, 0644)
+}
@@ -0,0 +1,378 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hjson/hjson-go/v4"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+type ConfigurationSourceKind string
+
+const (
+	DefaultConfigurationSource ConfigurationSourceKind = "default"
+	FileConfigurationSource    ConfigurationSourceKind = "file"
+	EnvConfigurationSource     ConfigurationSourceKind = "env"
+	FlagConfigurationSource    ConfigurationSourceKind = "flag"
+)
+
+type ConfigurationSource struct {
+	Kind ConfigurationSourceKind
+	// file path, environment variable or flag name the value came from
+	Origin string
+}
+
+func (s ConfigurationSource) String() string {
+	if s.Origin == "" {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s (%s)", s.Kind, s.Origin)
+}
+
+type ConfigurationOverride struct {
+	// dot separated path to the key, e.g. modules.tezbake.bakers
+	Key   string
+	Value string
+	// name of the flag the override came from
+	Origin string
+}
+
+type LoadOptions struct {
+	// path to the configuration file, takes precedence over TEZPEAK_CONFIG_FILE
+	ConfigFile string
+	// overrides applied on top of the file and environment, in order
+	Overrides []ConfigurationOverride
+}
+
+// EffectiveConfiguration is the merged configuration tree with the source of every value.
+// Layers are applied with precedence defaults < file < env < flags.
+type EffectiveConfiguration struct {
+	values  map[string]any
+	sources map[string]ConfigurationSource
+}
+
+func newEffectiveConfiguration() *EffectiveConfiguration {
+	return &EffectiveConfiguration{
+		values:  map[string]any{},
+		sources: map[string]ConfigurationSource{},
+	}
+}
+
+func joinConfigurationPath(path []string) string {
+	return strings.Join(path, ".")
+}
+
+func (e *EffectiveConfiguration) clearSources(path []string) {
+	prefix := joinConfigurationPath(path)
+	for key := range e.sources {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			delete(e.sources, key)
+		}
+	}
+}
+
+func (e *EffectiveConfiguration) recordSources(path []string, value any, source ConfigurationSource) {
+	if m, ok := value.(map[string]any); ok && len(m) > 0 {
+		for key, child := range m {
+			e.recordSources(append(slices.Clone(path), key), child, source)
+		}
+		return
+	}
+	e.sources[joinConfigurationPath(path)] = source
+}
+
+// lookupKey finds existing key case-insensitively so env variables (always upper case)
+// can target keys like `Tezos Foundation` or `app_root`
+func lookupKey(m map[string]any, key string) string {
+	if _, ok := m[key]; ok {
+		return key
+	}
+	for existing := range m {
+		if strings.EqualFold(existing, key) {
+			return existing
+		}
+	}
+	return key
+}
+
+func (e *EffectiveConfiguration) get(path []string) (any, bool) {
+	var current any = e.values
+	for _, key := range path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[lookupKey(m, key)]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// set replaces value at the path, creating intermediate objects as needed
+// and returns the path with keys resolved to their existing spelling
+func (e *EffectiveConfiguration) set(path []string, value any, source ConfigurationSource) []string {
+	resolved := make([]string, 0, len(path))
+	current := e.values
+	for i, key := range path {
+		key = lookupKey(current, key)
+		resolved = append(resolved, key)
+		if i == len(path)-1 {
+			current[key] = value
+			break
+		}
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			current[key] = next
+		}
+		current = next
+	}
+
+	e.clearSources(resolved)
+	e.recordSources(resolved, value, source)
+	return resolved
+}
+
+func (e *EffectiveConfiguration) merge(path []string, value any, source ConfigurationSource) {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) == 0 {
+		e.set(path, value, source)
+		return
+	}
+	for key, child := range m {
+		e.merge(append(slices.Clone(path), key), child, source)
+	}
+}
+
+func (e *EffectiveConfiguration) setDefault(key string, value any) {
+	if _, ok := e.get([]string{key}); ok {
+		return
+	}
+	// round trip through json to get the same representation as the other layers
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return
+	}
+	e.set([]string{key}, normalized, ConfigurationSource{Kind: DefaultConfigurationSource})
+}
+
+func (e *EffectiveConfiguration) Source(key string) (ConfigurationSource, bool) {
+	for path := strings.Split(key, "."); len(path) > 0; path = path[:len(path)-1] {
+		if source, ok := e.sources[joinConfigurationPath(path)]; ok {
+			return source, true
+		}
+	}
+	return ConfigurationSource{}, false
+}
+
+func (e *EffectiveConfiguration) Marshal() ([]byte, error) {
+	return json.Marshal(e.values)
+}
+
+func (e *EffectiveConfiguration) flatten(path []string, value any, acc map[string]any) {
+	if m, ok := value.(map[string]any); ok && len(m) > 0 {
+		for key, child := range m {
+			e.flatten(append(slices.Clone(path), key), child, acc)
+		}
+		return
+	}
+	acc[joinConfigurationPath(path)] = value
+}
+
+// String renders every leaf value with the layer it came from
+func (e *EffectiveConfiguration) String() string {
+	leaves := map[string]any{}
+	e.flatten(nil, e.values, leaves)
+
+	keys := make([]string, 0, len(leaves))
+	for key := range leaves {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		value, err := json.Marshal(leaves[key])
+		if err != nil {
+			value = []byte(fmt.Sprintf("%v", leaves[key]))
+		}
+		source, ok := e.Source(key)
+		if !ok {
+			source = ConfigurationSource{Kind: DefaultConfigurationSource}
+		}
+		fmt.Fprintf(&builder, "%s = %s # %s\n", key, value, source)
+	}
+	return builder.String()
+}
+
+// StringList accepts either a list or a comma separated string,
+// so list values can be set from environment variables and flags
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	result := StringList{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	*l = result
+	return nil
+}
+
+// parseOverrideValue converts raw env/flag value into the shape of the value it replaces.
+// Strings stay strings, lists accept comma separated values, everything else is parsed as json.
+func parseOverrideValue(raw string, current any) any {
+	raw = strings.TrimSpace(raw)
+	switch current.(type) {
+	case string:
+		return raw
+	case []any:
+		if !strings.HasPrefix(raw, "[") {
+			result := []any{}
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					result = append(result, item)
+				}
+			}
+			return result
+		}
+	}
+
+	var value any
+	if err := hjson.Unmarshal([]byte(raw), &value); err == nil {
+		if _, isString := value.(string); !isString || strings.HasPrefix(raw, "\"") || strings.HasPrefix(raw, "'") {
+			return value
+		}
+	}
+	return raw
+}
+
+// envKeyToPath maps TEZPEAK_MODULES__TEZBAKE__BAKERS to modules.tezbake.bakers
+func envKeyToPath(key string) []string {
+	key = strings.TrimPrefix(key, constants.ENV_TEZPEAK_PREFIX)
+	path := strings.Split(strings.ToLower(key), "__")
+	return slices.DeleteFunc(path, func(segment string) bool { return segment == "" })
+}
+
+func (e *EffectiveConfiguration) applyOverride(path []string, raw string, source ConfigurationSource) {
+	if len(path) == 0 {
+		return
+	}
+	current, _ := e.get(path)
+	e.set(path, parseOverrideValue(raw, current), source)
+}
+
+func (e *EffectiveConfiguration) applyEnvironment(environ []string) {
+	slices.Sort(environ) // deterministic order, parents before children
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, constants.ENV_TEZPEAK_PREFIX) {
+			continue
+		}
+		if slices.Contains(constants.ENV_TEZPEAK_RESERVED, key) {
+			continue
+		}
+		e.applyOverride(envKeyToPath(key), value, ConfigurationSource{Kind: EnvConfigurationSource, Origin: key})
+	}
+}
+
+func (e *EffectiveConfiguration) applyOverrides(overrides []ConfigurationOverride) {
+	for _, override := range overrides {
+		path := slices.DeleteFunc(strings.Split(override.Key, "."), func(segment string) bool { return segment == "" })
+		e.applyOverride(path, override.Value, ConfigurationSource{Kind: FlagConfigurationSource, Origin: override.Origin})
+	}
+}
+
+// ExtractOverrideArgs removes `--some.nested.key=value` and `--some.nested.key value` arguments
+// which the flag package does not know about and returns them as overrides.
+// Only names containing a dot are treated as configuration keys.
+func ExtractOverrideArgs(args []string) ([]string, []ConfigurationOverride) {
+	remaining := make([]string, 0, len(args))
+	overrides := []ConfigurationOverride{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			remaining = append(remaining, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			remaining = append(remaining, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		name, value, hasValue := strings.Cut(name, "=")
+		if !strings.Contains(name, ".") {
+			remaining = append(remaining, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				remaining = append(remaining, arg)
+				continue
+			}
+			i++
+			value = args[i]
+		}
+		overrides = append(overrides, ConfigurationOverride{
+			Key:    name,
+			Value:  value,
+			Origin: "--" + name,
+		})
+	}
+	return remaining, overrides
+}
+
+func getConfigurationDefaults() map[string]any {
+	defaults := map[string]any{}
+	data, err := json.Marshal(getDefault_v0())
+	if err != nil {
+		return defaults
+	}
+	_ = json.Unmarshal(data, &defaults)
+	return defaults
+}
+
+func loadEffectiveConfiguration(configFilePath string, options LoadOptions) (*EffectiveConfiguration, error) {
+	effective := newEffectiveConfiguration()
+	effective.merge(nil, getConfigurationDefaults(), ConfigurationSource{Kind: DefaultConfigurationSource})
+
+	configBytes, err := os.ReadFile(configFilePath)
+	if err == nil {
+		var fileValues map[string]any
+		if err := hjson.Unmarshal(configBytes, &fileValues); err != nil {
+			return nil, err
+		}
+		effective.merge(nil, fileValues, ConfigurationSource{Kind: FileConfigurationSource, Origin: configFilePath})
+	} else {
+		// continue with defaults, environment and flags
+		slog.Debug("failed to read config file", "error", err.Error())
+	}
+
+	effective.applyEnvironment(os.Environ())
+	effective.applyOverrides(options.Overrides)
+	return effective, nil
+}
@@ -0,0 +1,153 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvKeyToPath(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"TEZPEAK_LISTEN", []string{"listen"}},
+		{"TEZPEAK_LOG_LEVEL", []string{"log_level"}},
+		{"TEZPEAK_MODULES__TEZBAKE__BAKERS", []string{"modules", "tezbake", "bakers"}},
+		{"TEZPEAK_NODES__MY_NODE__URL", []string{"nodes", "my_node", "url"}},
+		{"TEZPEAK_MODULES____TEZBAKE__", []string{"modules", "tezbake"}},
+		{"TEZPEAK_", []string{}},
+	}
+	for _, test := range tests {
+		if path := envKeyToPath(test.key); !reflect.DeepEqual(path, test.expected) {
+			t.Errorf("envKeyToPath(%q) = %v, expected %v", test.key, path, test.expected)
+		}
+	}
+}
+
+func TestParseOverrideValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		current  any
+		expected any
+	}{
+		{"string stays string", "123", "old", "123"},
+		{"string is trimmed", "  value ", "old", "value"},
+		{"comma separated list", "a, b,,c", []any{"x"}, []any{"a", "b", "c"}},
+		{"json list", `["a", "b"]`, []any{}, []any{"a", "b"}},
+		{"number", "42", float64(1), float64(42)},
+		{"bool", "true", nil, true},
+		{"object", "{a: 1}", nil, map[string]any{"a": float64(1)}},
+		{"unquoted new string", "hello", nil, "hello"},
+		{"quoted new string", `"hello"`, nil, "hello"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := parseOverrideValue(test.raw, test.current); !reflect.DeepEqual(value, test.expected) {
+				t.Errorf("parseOverrideValue(%q) = %#v, expected %#v", test.raw, value, test.expected)
+			}
+		})
+	}
+}
+
+func TestExtractOverrideArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		remaining []string
+		overrides []ConfigurationOverride
+	}{
+		{
+			name:      "equals form",
+			args:      []string{"--modules.tezbake.bakers=tz1a,tz1b"},
+			remaining: []string{},
+			overrides: []ConfigurationOverride{{Key: "modules.tezbake.bakers", Value: "tz1a,tz1b", Origin: "--modules.tezbake.bakers"}},
+		},
+		{
+			name:      "separate value",
+			args:      []string{"-log.level", "debug", "run"},
+			remaining: []string{"run"},
+			overrides: []ConfigurationOverride{{Key: "log.level", Value: "debug", Origin: "--log.level"}},
+		},
+		{
+			name:      "flags without dot are kept",
+			args:      []string{"--log-level", "debug", "--listen=127.0.0.1:8733"},
+			remaining: []string{"--log-level", "debug", "--listen=127.0.0.1:8733"},
+			overrides: []ConfigurationOverride{},
+		},
+		{
+			name:      "missing value is kept",
+			args:      []string{"run", "--data.dir"},
+			remaining: []string{"run", "--data.dir"},
+			overrides: []ConfigurationOverride{},
+		},
+		{
+			name:      "arguments after terminator are kept",
+			args:      []string{"--a.b=1", "--", "--c.d=2"},
+			remaining: []string{"--", "--c.d=2"},
+			overrides: []ConfigurationOverride{{Key: "a.b", Value: "1", Origin: "--a.b"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			remaining, overrides := ExtractOverrideArgs(test.args)
+			if !reflect.DeepEqual(remaining, test.remaining) {
+				t.Errorf("remaining = %v, expected %v", remaining, test.remaining)
+			}
+			if !reflect.DeepEqual(overrides, test.overrides) {
+				t.Errorf("overrides = %v, expected %v", overrides, test.overrides)
+			}
+		})
+	}
+}
+
+func TestEffectiveConfigurationPrecedence(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.hjson")
+	err := os.WriteFile(configFile, []byte(`{
+		listen: 127.0.0.1:1000
+		log_level: warn
+		data_dir: file
+		modules: {
+			tezbake: {
+				bakers: [ "tz1file" ]
+			}
+		}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEZPEAK_LOG_LEVEL", "error")
+	t.Setenv("TEZPEAK_DATA_DIR", "env")
+	t.Setenv("TEZPEAK_MODULES__TEZBAKE__BAKERS", "tz1env1,tz1env2")
+
+	effective, err := loadEffectiveConfiguration(configFile, LoadOptions{
+		Overrides: []ConfigurationOverride{{Key: "data_dir", Value: "flag", Origin: "--data_dir"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key      string
+		expected any
+		source   ConfigurationSourceKind
+	}{
+		{"log_format", getDefault_v0().LogFormat, DefaultConfigurationSource},
+		{"listen", "127.0.0.1:1000", FileConfigurationSource},
+		{"log_level", "error", EnvConfigurationSource},
+		{"data_dir", "flag", FlagConfigurationSource},
+		{"modules.tezbake.bakers", []any{"tz1env1", "tz1env2"}, EnvConfigurationSource},
+	}
+	for _, test := range tests {
+		value, ok := effective.get(strings.Split(test.key, "."))
+		if !ok || !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("%s = %#v, expected %#v", test.key, value, test.expected)
+		}
+		if source, ok := effective.Source(test.key); !ok || source.Kind != test.source {
+			t.Errorf("source of %s = %s, expected %s", test.key, source, test.source)
+		}
+	}
+}
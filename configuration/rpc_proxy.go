@@ -0,0 +1,96 @@
+package configuration
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+// RpcProxyConfiguration configures read-only node RPC proxy at /api/rpc/*
+type RpcProxyConfiguration struct {
+	// allows the proxy in public mode, it is available only in private mode otherwise
+	Public bool `json:"public,omitempty"`
+	// path patterns allowed to be forwarded, * matches single path segment, trailing ** matches the rest of the path
+	Whitelist []string `json:"whitelist,omitempty"`
+	// responses larger than this are not forwarded, in bytes
+	MaxResponseSize int64 `json:"max_response_size,omitempty"`
+}
+
+func getDefaultRpcProxyConfiguration() RpcProxyConfiguration {
+	return RpcProxyConfiguration{
+		Whitelist: []string{
+			"version",
+			"chains/main/chain_id",
+			"chains/main/blocks/*",
+			"chains/main/blocks/*/hash",
+			"chains/main/blocks/*/header",
+			"chains/main/blocks/*/metadata",
+			"chains/main/blocks/*/protocols",
+			"chains/main/blocks/*/operations/**",
+			"chains/main/blocks/*/operation_hashes/**",
+			"chains/main/blocks/*/context/constants",
+			"chains/main/blocks/*/context/contracts/*",
+			"chains/main/blocks/*/context/contracts/*/**",
+			"chains/main/blocks/*/context/delegates/*",
+			"chains/main/blocks/*/context/delegates/*/**",
+			"chains/main/blocks/*/context/big_maps/*/*",
+			"chains/main/blocks/*/helpers/baking_rights",
+			"chains/main/blocks/*/helpers/attestation_rights",
+			"chains/main/blocks/*/votes/**",
+		},
+		MaxResponseSize: constants.DEFAULT_RPC_PROXY_MAX_RESPONSE_SIZE,
+	}
+}
+
+func (c *RpcProxyConfiguration) Hydrate() {
+	if c.MaxResponseSize <= 0 {
+		c.MaxResponseSize = constants.DEFAULT_RPC_PROXY_MAX_RESPONSE_SIZE
+	}
+}
+
+// Allows reports whether the rpc path matches any whitelisted pattern
+func (c *RpcProxyConfiguration) Allows(rpcPath string) bool {
+	segments := strings.Split(strings.Trim(rpcPath, "/"), "/")
+	for _, pattern := range c.Whitelist {
+		if matchRpcPathPattern(strings.Split(strings.Trim(pattern, "/"), "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRpcPathPattern(pattern []string, segments []string) bool {
+	for i, part := range pattern {
+		if part == "**" && i == len(pattern)-1 {
+			return len(segments) > i
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if segments[i] == "." || segments[i] == ".." {
+			return false
+		}
+		if ok, err := path.Match(part, segments[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
+
+func (v *Runtime) validateRpcProxy() []ValidationIssue {
+	issues := []ValidationIssue{}
+	for i, pattern := range v.RpcProxy.Whitelist {
+		for _, part := range strings.Split(strings.Trim(pattern, "/"), "/") {
+			if _, err := path.Match(part, ""); err != nil {
+				issues = append(issues, ValidationIssue{
+					Key: fmt.Sprintf("rpc_proxy.whitelist[%d]", i),
+					Err: fmt.Errorf("%w %q: %s", constants.ErrInvalidRpcPathPattern, pattern, err.Error()),
+				})
+				break
+			}
+		}
+	}
+	return issues
+}
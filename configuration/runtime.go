@@ -1,242 +1,394 @@
-package configuration
-
-import (
-	"encoding/json"
-	"errors"
-	"log/slog"
-	"net"
-	"os"
-	"path/filepath"
-
-	"github.com/hjson/hjson-go/v4"
-	"github.com/tez-capital/tezpeak/constants"
-)
-
-type moduleConfigurationbase struct {
-	Applications map[string]string `json:"applications,omitempty"`
-
-	Mode PeakMode `json:"mode,omitempty"`
-}
-
-type PeakMode string
-
-const (
-	PrivatePeakMode PeakMode = "private"
-	PublicPeakMode  PeakMode = "public"
-	AutoPeakMode    PeakMode = "auto"
-)
-
-type versionedConfig interface {
-	ToRuntime() *Runtime
-}
-
-type deserializedConfigVersion struct {
-	Version int `json:"version,omitempty"`
-}
-
-type TezosNode struct {
-	Address               string `json:"address"`
-	IsRightsProvider      bool   `json:"is_rights_provider,omitempty"`
-	IsBlockProvider       bool   `json:"is_block_provider,omitempty"`
-	IsGovernanceProvider  bool   `json:"is_governance_provider,omitempty"`
-	IsNetworkInfoProvider bool   `json:"is_network_info_provider,omitempty"`
-	IsEssential           bool   `json:"is_essential,omitempty"`
-	Priority              int    `json:"priority,omitempty"`
-}
-
-var (
-	TF_RPC = TezosNode{
-		Address:              "https://rpc.tzbeta.net/",
-		IsGovernanceProvider: true,
-	}
-	TZKT_RPC = TezosNode{
-		Address:              "https://rpc.tzkt.io/mainnet/",
-		IsBlockProvider:      true,
-		IsRightsProvider:     true,
-		IsGovernanceProvider: true,
-	}
-	TZC_EU_RPC = TezosNode{
-		Address:              "https://eu.rpc.tez.capital/",
-		IsBlockProvider:      true,
-		IsRightsProvider:     true,
-		IsGovernanceProvider: true,
-		Priority:             50,
-	}
-	TZC_US_RPC = TezosNode{
-		Address:              "https://us.rpc.tez.capital/",
-		IsBlockProvider:      true,
-		IsRightsProvider:     true,
-		IsGovernanceProvider: true,
-		Priority:             50,
-	}
-	BAKER_NODE = TezosNode{
-		Address:               "http://127.0.0.1:8732/",
-		IsRightsProvider:      true,
-		IsBlockProvider:       true,
-		IsGovernanceProvider:  true,
-		IsNetworkInfoProvider: true,
-		IsEssential:           true,
-		Priority:              100,
-	}
-)
-
-type Runtime struct {
-	Id     string
-	Listen string
-	Mode   PeakMode
-	// path to the root where are the apps located e.g. /bake-buddy
-	AppRoot string
-
-	Modules map[string]json.RawMessage `json:"modules,omitempty"`
-
-	Nodes map[string]TezosNode
-}
-
-func gerDefaultRuntime() *Runtime {
-	return &Runtime{
-		Id:      "",
-		Listen:  constants.DEFAULT_LISTEN_ADDRESS,
-		Mode:    AutoPeakMode,
-		Modules: map[string]json.RawMessage{},
-	}
-}
-
-func (v *Runtime) GetTezbakeModuleConfiguration() (bool, *TezbakeModuleConfiguration) {
-	rawConfiguration, ok := v.Modules[constants.TEZBAKE_MODULE_ID]
-	if !ok {
-		return false, nil
-	}
-
-	configuration := getDefaultTezbakeModuleConfiguration()
-	err := hjson.Unmarshal(rawConfiguration, configuration)
-	if err != nil {
-		slog.Error("failed to parse tezbake module configuration", "error", err.Error())
-		return false, nil
-	}
-
-	for key, value := range configuration.Applications {
-		if filepath.IsAbs(value) {
-			continue // skip absolute paths
-		}
-		if value == "" {
-			continue // skip empty paths
-		}
-		configuration.Applications[key] = filepath.Join(v.AppRoot, value)
-	}
-
-	if configuration.Mode == "" {
-		configuration.Mode = v.Mode
-	}
-	configuration.Hydrate()
-
-	if err := configuration.Validate(); err != nil {
-		slog.Error("failed to validate tezbake module configuration", "error", err.Error())
-		return false, nil
-	}
-
-	return true, configuration
-}
-
-func (v *Runtime) GetTezpayModuleConfiguration() (bool, *TezpayModuleConfiguration) {
-	rawConfiguration, ok := v.Modules[constants.TEZPAY_MODULE_ID]
-	if !ok {
-		return false, nil
-	}
-
-	configuration := getDefaultTezpayModuleConfiguration()
-	err := hjson.Unmarshal(rawConfiguration, configuration)
-	if err != nil {
-		slog.Error("failed to parse tezpay module configuration", "error", err.Error())
-		return false, nil
-	}
-
-	for key, value := range configuration.Applications {
-		if filepath.IsAbs(value) {
-			continue // skip absolute paths
-		}
-		configuration.Applications[key] = filepath.Join(v.AppRoot, value)
-	}
-
-	if configuration.Mode == "" {
-		configuration.Mode = v.Mode
-	}
-	configuration.Hydrate()
-
-	if err := configuration.Validate(); err != nil {
-		slog.Error("failed to validate tezpay module configuration", "error", err.Error())
-		return false, nil
-	}
-
-	return true, configuration
-}
-
-func (r *Runtime) Validate() (*Runtime, error) {
-	if r.Listen != "" {
-		_, _, err := net.SplitHostPort(r.Listen)
-		if err != nil {
-			return nil, constants.ErrInvalidListenAddress
-		}
-	}
-
-	if r.AppRoot == "" {
-		return nil, constants.ErrInvalidWorkingDirectory
-	}
-
-	// NOTE: should we validate child configurations and exit early if invalid?
-
-	return r, nil
-}
-
-func (r *Runtime) Hydrate() *Runtime {
-	if r.AppRoot == "" {
-		r.AppRoot, _ = os.Getwd()
-	}
-
-	if len(r.Nodes) == 0 {
-		r.Nodes = map[string]TezosNode{
-			"baker":  BAKER_NODE,
-			"TzC-EU": TZC_EU_RPC,
-			"TzC-US": TZC_US_RPC,
-			"TF":     TF_RPC,
-			"TZKT":   TZKT_RPC,
-		}
-	}
-
-	return r
-}
-
-func Load() (*Runtime, error) {
-	var err error
-	configFilePath := os.Getenv(constants.ENV_TEZPEAK_CONFIG_FILE)
-	if configFilePath == "" {
-		configFilePath = "config.hjson"
-	}
-
-	configBytes, err := os.ReadFile(configFilePath)
-	if err != nil {
-		slog.Debug("failed to read config file", "error", err.Error())
-
-		// return config loaded from environment variables
-		return gerDefaultRuntime().Hydrate().Validate()
-	}
-
-	var configVersion deserializedConfigVersion
-	err = hjson.Unmarshal(configBytes, &configVersion)
-	if err != nil {
-		return nil, errors.Join(constants.ErrInvalidConfigVersion, err)
-	}
-
-	var configuration versionedConfig
-	switch configVersion.Version {
-	case 0:
-		configuration, err = load_v0(configBytes)
-	default:
-		return nil, constants.ErrInvalidConfigVersion
-	}
-
-	if err != nil {
-		return nil, errors.Join(constants.ErrInvalidConfig, err)
-	}
-
-	return configuration.ToRuntime().Hydrate().Validate()
-}
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hjson/hjson-go/v4"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+type moduleConfigurationbase struct {
+	Applications map[string]string `json:"applications,omitempty"`
+
+	Mode PeakMode `json:"mode,omitempty"`
+	// inherited from runtime if not set
+	DataDir string `json:"data_dir,omitempty"`
+}
+
+type PeakMode string
+
+const (
+	PrivatePeakMode PeakMode = "private"
+	PublicPeakMode  PeakMode = "public"
+	AutoPeakMode    PeakMode = "auto"
+)
+
+type versionedConfig interface {
+	ToRuntime() *Runtime
+}
+
+type deserializedConfigVersion struct {
+	Version int `json:"version,omitempty"`
+}
+
+type TezosNode struct {
+	Address               string `json:"address"`
+	IsRightsProvider      bool   `json:"is_rights_provider,omitempty"`
+	IsBlockProvider       bool   `json:"is_block_provider,omitempty"`
+	IsGovernanceProvider  bool   `json:"is_governance_provider,omitempty"`
+	IsNetworkInfoProvider bool   `json:"is_network_info_provider,omitempty"`
+	IsEssential           bool   `json:"is_essential,omitempty"`
+	Priority              int    `json:"priority,omitempty"`
+	// network info providers alert when peer count drops below, default used if 0
+	MinPeers int `json:"min_peers,omitempty"`
+}
+
+var (
+	TF_RPC = TezosNode{
+		Address:              "https://rpc.tzbeta.net/",
+		IsGovernanceProvider: true,
+	}
+	TZKT_RPC = TezosNode{
+		Address:              "https://rpc.tzkt.io/mainnet/",
+		IsBlockProvider:      true,
+		IsRightsProvider:     true,
+		IsGovernanceProvider: true,
+	}
+	TZC_EU_RPC = TezosNode{
+		Address:              "https://eu.rpc.tez.capital/",
+		IsBlockProvider:      true,
+		IsRightsProvider:     true,
+		IsGovernanceProvider: true,
+		Priority:             50,
+	}
+	TZC_US_RPC = TezosNode{
+		Address:              "https://us.rpc.tez.capital/",
+		IsBlockProvider:      true,
+		IsRightsProvider:     true,
+		IsGovernanceProvider: true,
+		Priority:             50,
+	}
+	TEZTNETS_GHOSTNET_RPC = TezosNode{
+		Address:              "https://rpc.ghostnet.teztnets.com/",
+		IsBlockProvider:      true,
+		IsRightsProvider:     true,
+		IsGovernanceProvider: true,
+		Priority:             50,
+	}
+	TZKT_GHOSTNET_RPC = TezosNode{
+		Address:              "https://rpc.tzkt.io/ghostnet/",
+		IsBlockProvider:      true,
+		IsRightsProvider:     true,
+		IsGovernanceProvider: true,
+	}
+	BAKER_NODE = TezosNode{
+		Address:               "http://127.0.0.1:8732/",
+		IsRightsProvider:      true,
+		IsBlockProvider:       true,
+		IsGovernanceProvider:  true,
+		IsNetworkInfoProvider: true,
+		IsEssential:           true,
+		Priority:              100,
+	}
+)
+
+// getPublicNodes returns public nodes usable as fallbacks for the network
+func getPublicNodes(network string) map[string]TezosNode {
+	switch network {
+	case constants.MAINNET_NETWORK:
+		return map[string]TezosNode{
+			"TzC-EU": TZC_EU_RPC,
+			"TzC-US": TZC_US_RPC,
+			"TF":     TF_RPC,
+			"TZKT":   TZKT_RPC,
+		}
+	case constants.GHOSTNET_NETWORK:
+		return map[string]TezosNode{
+			"teztnets": TEZTNETS_GHOSTNET_RPC,
+			"TZKT":     TZKT_GHOSTNET_RPC,
+		}
+	default:
+		return map[string]TezosNode{}
+	}
+}
+
+// DefaultNodes returns baker node with public fallbacks for the network
+func DefaultNodes(network string) map[string]TezosNode {
+	nodes := getPublicNodes(network)
+	nodes["baker"] = BAKER_NODE
+	return nodes
+}
+
+type Runtime struct {
+	Id     string
+	Listen string
+	Mode   PeakMode
+	// network name, empty until detected if not configured
+	Network string
+	// path to the root where are the apps located e.g. /bake-buddy
+	AppRoot string
+
+	Modules map[string]json.RawMessage `json:"modules,omitempty"`
+
+	Nodes map[string]TezosNode
+	// blocks before the end of cycle the cycle ending event is emitted
+	CycleEndingBlocks int64
+	// directory tezpeak persists its caches and history in
+	DataDir string
+
+	RpcProxy RpcProxyConfiguration
+
+	Log util.LogOptions
+	// nodes were not configured and follow the network
+	defaultNodes bool
+	// file the configuration was loaded from
+	configFile string
+}
+
+func (v *Runtime) resolveApplicationPaths(applications map[string]string) {
+	for key, value := range applications {
+		if filepath.IsAbs(value) {
+			continue // skip absolute paths
+		}
+		if value == "" {
+			continue // skip empty paths
+		}
+		applications[key] = filepath.Join(v.AppRoot, value)
+	}
+}
+
+// LoadTezbakeModuleConfiguration parses, hydrates and validates tezbake module configuration.
+// Hydrated configuration is returned even if validation fails.
+func (v *Runtime) LoadTezbakeModuleConfiguration() (*TezbakeModuleConfiguration, error) {
+	rawConfiguration, ok := v.Modules[constants.TEZBAKE_MODULE_ID]
+	if !ok {
+		return nil, constants.ErrModuleNotConfigured
+	}
+
+	configuration := getDefaultTezbakeModuleConfiguration()
+	err := hjson.Unmarshal(rawConfiguration, configuration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", constants.ErrFailedToParseModuleConfiguration, err)
+	}
+
+	v.resolveApplicationPaths(configuration.Applications)
+
+	if configuration.Mode == "" {
+		configuration.Mode = v.Mode
+	}
+	if configuration.DataDir == "" {
+		configuration.DataDir = v.DataDir
+	}
+	configuration.Hydrate()
+
+	if err := configuration.Validate(); err != nil {
+		return configuration, errors.Join(constants.ErrInvalidModuleConfiguration, err)
+	}
+
+	return configuration, nil
+}
+
+func (v *Runtime) GetTezbakeModuleConfiguration() (bool, *TezbakeModuleConfiguration) {
+	configuration, err := v.LoadTezbakeModuleConfiguration()
+	if errors.Is(err, constants.ErrModuleNotConfigured) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("failed to load tezbake module configuration", "error", err.Error())
+		return false, nil
+	}
+
+	return true, configuration
+}
+
+// LoadTezpayModuleConfiguration parses, hydrates and validates tezpay module configuration.
+// Hydrated configuration is returned even if validation fails.
+func (v *Runtime) LoadTezpayModuleConfiguration() (*TezpayModuleConfiguration, error) {
+	rawConfiguration, ok := v.Modules[constants.TEZPAY_MODULE_ID]
+	if !ok {
+		return nil, constants.ErrModuleNotConfigured
+	}
+
+	configuration := getDefaultTezpayModuleConfiguration()
+	err := hjson.Unmarshal(rawConfiguration, configuration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", constants.ErrFailedToParseModuleConfiguration, err)
+	}
+
+	v.resolveApplicationPaths(configuration.Applications)
+
+	if configuration.Mode == "" {
+		configuration.Mode = v.Mode
+	}
+	if configuration.DataDir == "" {
+		configuration.DataDir = v.DataDir
+	}
+	configuration.Hydrate()
+
+	if err := configuration.Validate(); err != nil {
+		return configuration, errors.Join(constants.ErrInvalidModuleConfiguration, err)
+	}
+
+	return configuration, nil
+}
+
+func (v *Runtime) GetTezpayModuleConfiguration() (bool, *TezpayModuleConfiguration) {
+	configuration, err := v.LoadTezpayModuleConfiguration()
+	if errors.Is(err, constants.ErrModuleNotConfigured) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("failed to load tezpay module configuration", "error", err.Error())
+		return false, nil
+	}
+
+	return true, configuration
+}
+
+// LoadHostModuleConfiguration parses, hydrates and validates host module configuration.
+// Hydrated configuration is returned even if validation fails.
+func (v *Runtime) LoadHostModuleConfiguration() (*HostModuleConfiguration, error) {
+	rawConfiguration, ok := v.Modules[constants.HOST_MODULE_ID]
+	if !ok {
+		return nil, constants.ErrModuleNotConfigured
+	}
+
+	configuration := getDefaultHostModuleConfiguration()
+	err := hjson.Unmarshal(rawConfiguration, configuration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", constants.ErrFailedToParseModuleConfiguration, err)
+	}
+
+	v.resolveApplicationPaths(configuration.Applications)
+
+	if configuration.Mode == "" {
+		configuration.Mode = v.Mode
+	}
+	if configuration.DataDir == "" {
+		configuration.DataDir = v.DataDir
+	}
+	configuration.Hydrate()
+
+	if err := configuration.Validate(); err != nil {
+		return configuration, errors.Join(constants.ErrInvalidModuleConfiguration, err)
+	}
+
+	return configuration, nil
+}
+
+func (v *Runtime) GetHostModuleConfiguration() (bool, *HostModuleConfiguration) {
+	configuration, err := v.LoadHostModuleConfiguration()
+	if errors.Is(err, constants.ErrModuleNotConfigured) {
+		return false, nil
+	}
+	if err != nil {
+		slog.Error("failed to load host module configuration", "error", err.Error())
+		return false, nil
+	}
+
+	return true, configuration
+}
+
+func (r *Runtime) Validate() (*Runtime, error) {
+	if r.Listen != "" {
+		_, _, err := net.SplitHostPort(r.Listen)
+		if err != nil {
+			return nil, constants.ErrInvalidListenAddress
+		}
+	}
+
+	if r.AppRoot == "" {
+		return nil, constants.ErrInvalidWorkingDirectory
+	}
+
+	// NOTE: child configurations are validated by ValidateAll (tezpeak config validate)
+
+	return r, nil
+}
+
+func (r *Runtime) Hydrate() *Runtime {
+	if r.AppRoot == "" {
+		r.AppRoot, _ = os.Getwd()
+	}
+	r.Network = strings.ToLower(strings.TrimSpace(r.Network))
+	if r.DataDir == "" {
+		r.DataDir = constants.DEFAULT_DATA_DIR
+	}
+	r.RpcProxy.Hydrate()
+
+	if len(r.Nodes) == 0 {
+		network := r.Network
+		if network == "" {
+			network = constants.DEFAULT_NETWORK
+		}
+		r.Nodes = DefaultNodes(network)
+		r.defaultNodes = true
+	}
+
+	return r
+}
+
+func Load() (*Runtime, error) {
+	runtime, _, err := LoadWithOptions(LoadOptions{})
+	return runtime, err
+}
+
+// LoadWithOptions loads configuration layered as defaults < file < env < flags
+// and returns the effective configuration alongside the runtime
+func LoadWithOptions(options LoadOptions) (*Runtime, *EffectiveConfiguration, error) {
+	var err error
+	configFilePath := options.ConfigFile
+	if configFilePath == "" {
+		configFilePath = os.Getenv(constants.ENV_TEZPEAK_CONFIG_FILE)
+	}
+	if configFilePath == "" {
+		configFilePath = constants.DEFAULT_CONFIG_FILE
+	}
+
+	effective, err := loadEffectiveConfiguration(configFilePath, options)
+	if err != nil {
+		return nil, nil, errors.Join(constants.ErrInvalidConfig, err)
+	}
+
+	configBytes, err := effective.Marshal()
+	if err != nil {
+		return nil, nil, errors.Join(constants.ErrInvalidConfig, err)
+	}
+
+	var configVersion deserializedConfigVersion
+	err = hjson.Unmarshal(configBytes, &configVersion)
+	if err != nil {
+		return nil, nil, errors.Join(constants.ErrInvalidConfigVersion, err)
+	}
+
+	var configuration versionedConfig
+	switch configVersion.Version {
+	case 0:
+		configuration, err = load_v0(configBytes)
+	default:
+		return nil, nil, constants.ErrInvalidConfigVersion
+	}
+
+	if err != nil {
+		return nil, nil, errors.Join(constants.ErrInvalidConfig, err)
+	}
+
+	runtime := configuration.ToRuntime().Hydrate()
+	runtime.configFile = configFilePath
+	effective.setDefault("app_root", runtime.AppRoot)
+	effective.setDefault("nodes", runtime.Nodes)
+
+	// hydrated runtime is returned even if invalid so it can be inspected
+	if _, err := runtime.Validate(); err != nil {
+		return runtime, effective, err
+	}
+	return runtime, effective, nil
+}
@@ -1,54 +1,99 @@
-package configuration
-
-import (
-	"encoding/json"
-
-	"github.com/hjson/hjson-go/v4"
-	"github.com/tez-capital/tezpeak/constants"
-)
-
-type v0 struct {
-	Version int      `json:"version,omitempty"`
-	Id      string   `json:"id,omitempty"`
-	AppRoot string   `json:"app_root,omitempty"`
-	Listen  string   `json:"listen,omitempty"`
-	Mode    PeakMode `json:"mode,omitempty"`
-
-	Modules map[string]json.RawMessage `json:"modules,omitempty"`
-
-	Nodes map[string]TezosNode `json:"nodes,omitempty"`
-}
-
-func getDefault_v0() *v0 {
-	return &v0{
-		Version: 0,
-		AppRoot: "",
-		Listen:  constants.DEFAULT_LISTEN_ADDRESS,
-		Mode:    AutoPeakMode,
-		Modules: map[string]json.RawMessage{},
-	}
-}
-
-func load_v0(configBytes []byte) (*v0, error) {
-	configuration := getDefault_v0()
-
-	err := hjson.Unmarshal(configBytes, &configuration)
-	if err != nil {
-		return nil, err
-	}
-
-	return configuration, nil
-}
-
-func (v *v0) ToRuntime() *Runtime {
-	result := &Runtime{
-		Id:     v.Id,
-		Listen: v.Listen,
-		Mode:   v.Mode,
-
-		AppRoot: v.AppRoot,
-
-		Modules: v.Modules,
-	}
-	return result
-}
+package configuration
+
+import (
+	"encoding/json"
+
+	"github.com/hjson/hjson-go/v4"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+type v0 struct {
+	Version int      `json:"version,omitempty"`
+	Id      string   `json:"id,omitempty"`
+	AppRoot string   `json:"app_root,omitempty"`
+	Listen  string   `json:"listen,omitempty"`
+	Mode    PeakMode `json:"mode,omitempty"`
+	// mainnet, ghostnet or custom network name, detected from the baker node chain id if empty
+	Network string `json:"network,omitempty"`
+
+	Modules map[string]json.RawMessage `json:"modules,omitempty"`
+
+	Nodes map[string]TezosNode `json:"nodes,omitempty"`
+	// blocks before the end of cycle modules are notified about approaching cycle end
+	CycleEndingBlocks int64 `json:"cycle_ending_blocks,omitempty"`
+	// directory tezpeak persists its caches and history in
+	DataDir string `json:"data_dir,omitempty"`
+
+	RpcProxy RpcProxyConfiguration `json:"rpc_proxy,omitempty"`
+
+	LogLevel          string            `json:"log_level,omitempty"`
+	LogFormat         string            `json:"log_format,omitempty"`
+	LogFile           string            `json:"log_file,omitempty"`
+	LogFileMaxSize    int               `json:"log_file_max_size,omitempty"`
+	LogFileMaxAge     int               `json:"log_file_max_age,omitempty"`
+	LogFileMaxBackups int               `json:"log_file_max_backups,omitempty"`
+	LogLevels         map[string]string `json:"log_levels,omitempty"`
+}
+
+func getDefault_v0() *v0 {
+	return &v0{
+		Version: 0,
+		AppRoot: "",
+		Listen:  constants.DEFAULT_LISTEN_ADDRESS,
+		Mode:    AutoPeakMode,
+		Modules: map[string]json.RawMessage{},
+
+		CycleEndingBlocks: constants.DEFAULT_CYCLE_ENDING_BLOCKS,
+		DataDir:           constants.DEFAULT_DATA_DIR,
+		RpcProxy:          getDefaultRpcProxyConfiguration(),
+
+		LogLevel:          constants.DEFAULT_LOG_LEVEL,
+		LogFormat:         constants.DEFAULT_LOG_FORMAT,
+		LogFileMaxSize:    constants.DEFAULT_LOG_FILE_MAX_SIZE,
+		LogFileMaxAge:     constants.DEFAULT_LOG_FILE_MAX_AGE,
+		LogFileMaxBackups: constants.DEFAULT_LOG_FILE_MAX_BACKUPS,
+	}
+}
+
+func load_v0(configBytes []byte) (*v0, error) {
+	configuration := getDefault_v0()
+
+	err := hjson.Unmarshal(configBytes, &configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return configuration, nil
+}
+
+func (v *v0) ToRuntime() *Runtime {
+	result := &Runtime{
+		Id:     v.Id,
+		Listen: v.Listen,
+		Mode:   v.Mode,
+
+		Network: v.Network,
+
+		AppRoot: v.AppRoot,
+
+		Modules: v.Modules,
+
+		Nodes: v.Nodes,
+
+		CycleEndingBlocks: v.CycleEndingBlocks,
+		DataDir:           v.DataDir,
+		RpcProxy:          v.RpcProxy,
+
+		Log: util.LogOptions{
+			Level:          v.LogLevel,
+			Format:         v.LogFormat,
+			File:           v.LogFile,
+			FileMaxSize:    v.LogFileMaxSize,
+			FileMaxAge:     v.LogFileMaxAge,
+			FileMaxBackups: v.LogFileMaxBackups,
+			ModuleLevels:   v.LogLevels,
+		},
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hjson/hjson-go/v4"
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/util"
+	"github.com/trilitech/tzgo/tezos"
+)
+
+// ValidationIssue is a validation error bound to the configuration key it was found at
+type ValidationIssue struct {
+	Key string
+	Err error
+}
+
+func (i ValidationIssue) Error() string {
+	if i.Key == "" {
+		return i.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", i.Key, i.Err.Error())
+}
+
+func (i ValidationIssue) Unwrap() error {
+	return i.Err
+}
+
+func isValidHttpUrl(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// collectIssues flattens joined errors into issues prefixed with the key of the module
+func collectIssues(prefix string, err error) []ValidationIssue {
+	if err == nil || err == constants.ErrInvalidModuleConfiguration {
+		return nil
+	}
+
+	if issue, ok := err.(ValidationIssue); ok {
+		return []ValidationIssue{{Key: prefix + "." + issue.Key, Err: issue.Err}}
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok && !errors.Is(err, constants.ErrFailedToParseModuleConfiguration) {
+		issues := []ValidationIssue{}
+		for _, err := range joined.Unwrap() {
+			issues = append(issues, collectIssues(prefix, err)...)
+		}
+		return issues
+	}
+
+	return []ValidationIssue{{Key: prefix, Err: err}}
+}
+
+func (v *Runtime) validateNodes() []ValidationIssue {
+	issues := []ValidationIssue{}
+	if len(v.Nodes) == 0 {
+		return append(issues, ValidationIssue{Key: "nodes", Err: fmt.Errorf("%w: no nodes configured", constants.ErrInvalidNodes)})
+	}
+
+	ids := lo.Keys(v.Nodes)
+	slices.Sort(ids)
+	for _, id := range ids {
+		node := v.Nodes[id]
+		if !isValidHttpUrl(node.Address) {
+			issues = append(issues, ValidationIssue{Key: fmt.Sprintf("nodes.%s.address", id), Err: fmt.Errorf("%w %q", constants.ErrInvalidNodeUrl, node.Address)})
+		}
+	}
+
+	if !lo.SomeBy(lo.Values(v.Nodes), func(node TezosNode) bool { return node.IsBlockProvider }) {
+		issues = append(issues, ValidationIssue{Key: "nodes", Err: fmt.Errorf("%w: no block provider configured", constants.ErrInvalidNodes)})
+	}
+	if !lo.SomeBy(lo.Values(v.Nodes), func(node TezosNode) bool { return node.IsRightsProvider }) {
+		issues = append(issues, ValidationIssue{Key: "nodes", Err: fmt.Errorf("%w: no rights provider configured", constants.ErrInvalidNodes)})
+	}
+	return issues
+}
+
+type rawBakersConfiguration struct {
+	Bakers StringList `json:"bakers"`
+}
+
+func (v *Runtime) validateTezbakeModule() []ValidationIssue {
+	prefix := "modules." + constants.TEZBAKE_MODULE_ID
+	issues := []ValidationIssue{}
+
+	// invalid bakers are dropped during hydration, so check them on the raw configuration
+	var raw rawBakersConfiguration
+	if err := hjson.Unmarshal(v.Modules[constants.TEZBAKE_MODULE_ID], &raw); err == nil {
+		for i, baker := range raw.Bakers {
+			if _, err := tezos.ParseAddress(baker); err != nil {
+				issues = append(issues, ValidationIssue{Key: fmt.Sprintf("%s.bakers[%d]", prefix, i), Err: fmt.Errorf("invalid baker address %q", baker)})
+			}
+		}
+	}
+
+	_, err := v.LoadTezbakeModuleConfiguration()
+	return append(issues, collectIssues(prefix, err)...)
+}
+
+func (v *Runtime) validateTezpayModule() []ValidationIssue {
+	_, err := v.LoadTezpayModuleConfiguration()
+	return collectIssues("modules."+constants.TEZPAY_MODULE_ID, err)
+}
+
+func (v *Runtime) validateHostModule() []ValidationIssue {
+	_, err := v.LoadHostModuleConfiguration()
+	return collectIssues("modules."+constants.HOST_MODULE_ID, err)
+}
+
+// ValidationWarnings reports configuration which is valid but likely not intended
+func (v *Runtime) ValidationWarnings() []ValidationIssue {
+	warnings := []ValidationIssue{}
+	if len(v.Modules) == 0 {
+		warnings = append(warnings, ValidationIssue{Key: "modules", Err: constants.ErrNoModulesConfigured})
+	}
+	return warnings
+}
+
+// ValidateAll validates runtime and all module configurations and reports every issue found
+func (v *Runtime) ValidateAll() []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	if v.Listen != "" {
+		if _, _, err := net.SplitHostPort(v.Listen); err != nil {
+			issues = append(issues, ValidationIssue{Key: "listen", Err: fmt.Errorf("%w %q", constants.ErrInvalidListenAddress, v.Listen)})
+		}
+	}
+
+	if v.AppRoot == "" {
+		issues = append(issues, ValidationIssue{Key: "app_root", Err: constants.ErrInvalidWorkingDirectory})
+	} else if info, err := os.Stat(v.AppRoot); err != nil || !info.IsDir() {
+		issues = append(issues, ValidationIssue{Key: "app_root", Err: fmt.Errorf("%w %q: not a directory", constants.ErrInvalidWorkingDirectory, v.AppRoot)})
+	}
+
+	switch v.Mode {
+	case AutoPeakMode, PublicPeakMode, PrivatePeakMode:
+	default:
+		issues = append(issues, ValidationIssue{Key: "mode", Err: fmt.Errorf("%w %q, expected one of auto, public, private", constants.ErrInvalidMode, v.Mode)})
+	}
+
+	if _, err := util.ParseLogLevel(v.Log.Level); err != nil {
+		issues = append(issues, ValidationIssue{Key: "log_level", Err: err})
+	}
+	switch strings.ToLower(v.Log.Format) {
+	case util.TextLogFormat, util.JsonLogFormat, "":
+	default:
+		issues = append(issues, ValidationIssue{Key: "log_format", Err: fmt.Errorf("invalid log format %q, expected one of text, json", v.Log.Format)})
+	}
+	modules := lo.Keys(v.Log.ModuleLevels)
+	slices.Sort(modules)
+	for _, module := range modules {
+		if _, err := util.ParseLogLevel(v.Log.ModuleLevels[module]); err != nil {
+			issues = append(issues, ValidationIssue{Key: "log_levels." + module, Err: err})
+		}
+	}
+
+	issues = append(issues, v.validateNodes()...)
+	issues = append(issues, v.validateRpcProxy()...)
+
+	moduleIds := lo.Keys(v.Modules)
+	slices.Sort(moduleIds)
+	for _, id := range moduleIds {
+		switch id {
+		case constants.TEZBAKE_MODULE_ID:
+			issues = append(issues, v.validateTezbakeModule()...)
+		case constants.TEZPAY_MODULE_ID:
+			issues = append(issues, v.validateTezpayModule()...)
+		case constants.HOST_MODULE_ID:
+			issues = append(issues, v.validateHostModule()...)
+		default:
+			issues = append(issues, ValidationIssue{Key: "modules." + id, Err: constants.ErrUnknownModule})
+		}
+	}
+
+	return issues
+}
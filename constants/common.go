@@ -1,38 +1,139 @@
-package constants
-
-const (
-	TEZPEAK_VERSION  = "<VERSION>"
-	TEZPEAK_CODENAME = "<CODENAME>"
-
-	DEFAULT_LISTEN_ADDRESS       = "localhost:8733"
-	DEFAULT_HTTP_TIMEOUT_SECONDS = 30
-
-	// tezbake
-	TEZBAKE_MODULE_ID             = "tezbake"
-	ENV_TEZPEAK_CONFIG_FILE       = "TEZPEAK_CONFIG_FILE"
-	MAX_SERVICES_REFRESH_INTERVAL = 300 // 5 minutes
-	MIN_SERVICES_REFRESH_INTERVAL = 5   // 5 seconds
-	DEFAULT_NODE_APP_PATH         = "node"
-	DEFAULT_SIGNER_APP_PATH       = "signer"
-	DEFAULT_BAKER_NODE_URL        = "http://localhost:8732"
-	DEFAULT_BAKER_SIGNER_URL      = "http://localhost:20090"
-	DEFAULT_RIGHTS_BLOCK_WINDOW   = 50
-	DEFAULT_MONITOR_LEDGER_STATUS = true
-	DEFAULT_ARC_BINARY_PATH       = ""
-
-	// tezpay
-	TEZPAY_MODULE_ID        = "tezpay"
-	DEFAULT_TEZPAY_APP_PATH = "pay"
-
-	// tx constants
-	MAX_OPERATION_TTL         = 12
-	MAX_WAIT_FOR_CONFIRMATION = 120
-)
-
-var (
-	PRIVATE_NETWORK_HOSTS = []string{
-		"localhost",
-		"127.0.0.1",
-		"::1",
-	}
-)
+package constants
+
+const (
+	TEZPEAK_VERSION  = "<VERSION>"
+	TEZPEAK_CODENAME = "<CODENAME>"
+
+	DEFAULT_LISTEN_ADDRESS       = "localhost:8733"
+	DEFAULT_HTTP_TIMEOUT_SECONDS = 30
+	DEFAULT_CONFIG_FILE          = "config.hjson"
+	ENV_TEZPEAK_PREFIX           = "TEZPEAK_"
+	DOCTOR_HTTP_TIMEOUT_SECONDS  = 10
+
+	// logging
+	DEFAULT_LOG_LEVEL            = "info"
+	DEFAULT_LOG_FORMAT           = "text"
+	DEFAULT_LOG_FILE_MAX_SIZE    = 100 // MB
+	DEFAULT_LOG_FILE_MAX_AGE     = 7   // days
+	DEFAULT_LOG_FILE_MAX_BACKUPS = 5
+
+	// tezbake
+	TEZBAKE_MODULE_ID                = "tezbake"
+	ENV_TEZPEAK_CONFIG_FILE          = "TEZPEAK_CONFIG_FILE"
+	MAX_SERVICES_REFRESH_INTERVAL    = 300 // 5 minutes
+	MIN_SERVICES_REFRESH_INTERVAL    = 5   // 5 seconds
+	DEFAULT_NODE_APP_PATH            = "node"
+	DEFAULT_SIGNER_APP_PATH          = "signer"
+	DEFAULT_BAKER_NODE_URL           = "http://localhost:8732"
+	DEFAULT_NODE_RPC_PORT            = "8732"
+	DEFAULT_BAKER_SIGNER_URL         = "http://localhost:20090"
+	DEFAULT_RIGHTS_BLOCK_WINDOW      = 50
+	DEFAULT_MAINTENANCE_WINDOW_HOURS = 24
+	DEFAULT_MAX_BAKING_ROUND         = 2
+	DEFAULT_MONITOR_LEDGER_STATUS    = true
+	DEFAULT_ARC_BINARY_PATH          = ""
+	MIN_ARC_BINARY_VERSION           = "v0.0.12"
+
+	// tezpay
+	TEZPAY_MODULE_ID        = "tezpay"
+	DEFAULT_TEZPAY_APP_PATH = "pay"
+
+	// host
+	HOST_MODULE_ID                              = "host"
+	HOST_STATUS_INTERVAL                        = 30 // seconds
+	HOST_CLOCK_OFFSET_BLOCKS                    = 10
+	DEFAULT_HOST_DISK_WARNING_THRESHOLD         = 85   // percent
+	DEFAULT_HOST_DISK_ERROR_THRESHOLD           = 95   // percent
+	DEFAULT_HOST_MEMORY_WARNING_THRESHOLD       = 90   // percent
+	DEFAULT_HOST_MEMORY_ERROR_THRESHOLD         = 97   // percent
+	DEFAULT_HOST_SWAP_WARNING_THRESHOLD         = 50   // percent
+	DEFAULT_HOST_SWAP_ERROR_THRESHOLD           = 80   // percent
+	DEFAULT_HOST_LOAD_WARNING_THRESHOLD         = 1.5  // 1 minute load per cpu
+	DEFAULT_HOST_LOAD_ERROR_THRESHOLD           = 3    // 1 minute load per cpu
+	DEFAULT_HOST_CLOCK_OFFSET_WARNING_THRESHOLD = 1000 // milliseconds
+	DEFAULT_HOST_CLOCK_OFFSET_ERROR_THRESHOLD   = 3000 // milliseconds
+
+	// networks
+	MAINNET_NETWORK  = "mainnet"
+	GHOSTNET_NETWORK = "ghostnet"
+	DEFAULT_NETWORK  = MAINNET_NETWORK
+
+	MAINNET_CHAIN_ID  = "NetXdQprcVkpaWU"
+	GHOSTNET_CHAIN_ID = "NetXnHfVqm9iesp"
+
+	NODE_CHAIN_VERIFICATION_INTERVAL = 30 // seconds
+
+	// node pool
+	NODE_POOL_HEALTH_CHECK_INTERVAL     = 10 // seconds
+	NODE_POOL_HEALTH_CHECK_TIMEOUT      = 5  // seconds
+	NODE_POOL_CIRCUIT_FAILURE_THRESHOLD = 3
+	NODE_POOL_CIRCUIT_MIN_BACKOFF       = 5   // seconds
+	NODE_POOL_CIRCUIT_MAX_BACKOFF       = 300 // seconds
+	NODE_POOL_EWMA_ALPHA                = 0.2
+
+	NODE_CAPABILITY_PROBE_TIMEOUT  = 5  // seconds
+	NODE_CAPABILITY_RETRY_INTERVAL = 60 // seconds
+
+	NODE_SYNC_CHECK_INTERVAL    = 10 // seconds
+	NODE_SYNC_STUCK_BLOCK_TIMES = 4  // node without new head for longer is stuck
+	NODE_SYNC_TRANSITIONS       = 10
+	// cycle ending event is emitted this many blocks before the end of cycle
+	DEFAULT_CYCLE_ENDING_BLOCKS   = 120
+	DEFAULT_DATA_DIR              = "data"
+	PROTOCOL_CONSTANTS_CACHE_FILE = "constants.json"
+	RIGHTS_CACHE_DIR              = "rights"
+	// cached rights of older cycles are removed
+	RIGHTS_CACHE_PAST_CYCLES    = 5
+	RIGHTS_CACHE_RETRY_INTERVAL = 60 // seconds
+	MISS_HISTORY_DIR            = "misses"
+	// history of older cycles is removed
+	MISS_HISTORY_CYCLES = 30
+	// levels remembered to not persist the same check again
+	MISS_HISTORY_RECORDED_LEVELS = 200
+	DEFAULT_MISS_HISTORY_LIMIT   = 100
+
+	DEFAULT_RPC_PROXY_MAX_RESPONSE_SIZE = 5 * 1024 * 1024 // bytes
+	RPC_PROXY_CACHE_TTL                 = 60              // seconds
+	RPC_PROXY_CACHE_ENTRIES             = 256
+	// blocks this deep below the head are final and their data is cached
+	RPC_PROXY_FINAL_DEPTH = 2
+	// used until block time is known from block history or protocol constants
+	DEFAULT_BLOCK_TIME = 8 // seconds
+
+	NETWORK_DETECTION_RETRY_INTERVAL = 5 // seconds
+
+	NODE_VERSION_CHECK_INTERVAL = 3600 // seconds
+	NODE_NETWORK_INFO_INTERVAL  = 30   // seconds
+	NODE_NETWORK_INFO_TOP_PEERS = 5
+	DEFAULT_NODE_MIN_PEERS      = 5
+
+	MEMPOOL_MONITOR_RETRY_INTERVAL = 5    // seconds
+	MEMPOOL_SEEN_OPERATION_TTL     = 3600 // seconds
+	MEMPOOL_RECENT_OPERATIONS      = 20
+	MEMPOOL_SLOTS_CACHE_LEVELS     = 5
+
+	// levels below the network head kept to follow the canonical branch
+	CONSENSUS_LEVEL_WINDOW = 10
+	// levels of the head chain kept to detect replaced blocks
+	CHAIN_WINDOW_LEVELS = 10
+	// levels of blocks kept in block history
+	BLOCK_HISTORY_LEVELS        = 100
+	DEFAULT_BLOCK_HISTORY_LIMIT = 20
+
+	// tx constants
+	MAX_OPERATION_TTL         = 12
+	MAX_WAIT_FOR_CONFIRMATION = 120
+)
+
+var (
+	// TEZPEAK_ variables which are not configuration overrides
+	ENV_TEZPEAK_RESERVED = []string{
+		ENV_TEZPEAK_CONFIG_FILE,
+	}
+
+	PRIVATE_NETWORK_HOSTS = []string{
+		"localhost",
+		"127.0.0.1",
+		"::1",
+	}
+)
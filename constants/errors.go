@@ -1,28 +1,47 @@
-package constants
-
-import "errors"
-
-var (
-	ErrInvalidListenAddress    = errors.New("invalid listen address")
-	ErrInvalidWorkingDirectory = errors.New("invalid working directory")
-	ErrInvalidBlockWindow      = errors.New("invalid block window")
-	ErrInvalidConfigVersion    = errors.New("invalid configuration version")
-	ErrInvalidConfig           = errors.New("invalid configuration")
-	ErrInvalidSignerUrl        = errors.New("invalid signer url")
-	ErrInvalidNodeUrl          = errors.New("invalid node url")
-	ErrInvalidNodes            = errors.New("invalid nodes")
-	ErrNoValidBakers           = errors.New("no valid bakers")
-	ErrInvalidPayoutWallet     = errors.New("invalid payout wallet")
-	ErrNoTezpayAppPath         = errors.New("no tezpay app path")
-
-	ErrFailedToSignOperation      = errors.New("failed to sign operation")
-	ErrFailedToCompleteOperation  = errors.New("failed to complete operation")
-	ErrFailedToGetPublicKey       = errors.New("failed to get public key")
-	ErrFailedToCreateRemoteSigner = errors.New("failed to create remote signer")
-	ErrFailedToBroadcastOperation = errors.New("failed to broadcast operation")
-	ErrDelegateNotRegistered      = errors.New("delegate not registered")
-
-	ErrArcBinaryVersionCheckFailed = errors.New("arc binary version check failed")
-	ErrInvalidArcBinaryVersion     = errors.New("invalid arc binary version")
-	ErrArcBinaryVersionTooOld      = errors.New("arc binary version too old")
-)
+package constants
+
+import "errors"
+
+var (
+	ErrInvalidListenAddress    = errors.New("invalid listen address")
+	ErrInvalidWorkingDirectory = errors.New("invalid working directory")
+	ErrInvalidBlockWindow      = errors.New("invalid block window")
+	ErrInvalidConfigVersion    = errors.New("invalid configuration version")
+	ErrInvalidConfig           = errors.New("invalid configuration")
+	ErrInvalidSignerUrl        = errors.New("invalid signer url")
+	ErrInvalidNodeUrl          = errors.New("invalid node url")
+	ErrInvalidNodes            = errors.New("invalid nodes")
+	ErrNoValidBakers           = errors.New("no valid bakers")
+	ErrInvalidPayoutWallet     = errors.New("invalid payout wallet")
+	ErrNoTezpayAppPath         = errors.New("no tezpay app path")
+	ErrInvalidMode             = errors.New("invalid mode")
+	ErrUnknownModule           = errors.New("unknown module")
+	ErrNoModulesConfigured     = errors.New("no modules configured, only nodes are monitored")
+	ErrUnsupportedPlatform     = errors.New("unsupported platform")
+	ErrInvalidThresholds       = errors.New("warning threshold must not be above error threshold")
+	ErrNodeChainMismatch       = errors.New("node chain mismatch")
+	ErrNetworkNotDetected      = errors.New("network not detected")
+	ErrNoAvailableNode         = errors.New("no available node")
+	ErrNodeNotApplicable       = errors.New("node not applicable")
+	ErrNodeNotFound            = errors.New("node not found")
+	ErrNodeAlreadyExists       = errors.New("node already exists")
+	ErrNodeChanged             = errors.New("node changed meanwhile")
+	ErrNoConfigurationFile     = errors.New("no configuration file")
+	ErrInvalidRpcPathPattern   = errors.New("invalid rpc path pattern")
+	ErrRpcResponseTooLarge     = errors.New("rpc response too large")
+
+	ErrModuleNotConfigured              = errors.New("module not configured")
+	ErrFailedToParseModuleConfiguration = errors.New("failed to parse module configuration")
+	ErrInvalidModuleConfiguration       = errors.New("invalid module configuration")
+
+	ErrFailedToSignOperation      = errors.New("failed to sign operation")
+	ErrFailedToCompleteOperation  = errors.New("failed to complete operation")
+	ErrFailedToGetPublicKey       = errors.New("failed to get public key")
+	ErrFailedToCreateRemoteSigner = errors.New("failed to create remote signer")
+	ErrFailedToBroadcastOperation = errors.New("failed to broadcast operation")
+	ErrDelegateNotRegistered      = errors.New("delegate not registered")
+
+	ErrArcBinaryVersionCheckFailed = errors.New("arc binary version check failed")
+	ErrInvalidArcBinaryVersion     = errors.New("invalid arc binary version")
+	ErrArcBinaryVersionTooOld      = errors.New("arc binary version too old")
+)
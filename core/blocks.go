@@ -0,0 +1,17 @@
+package core
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+func registerBlocksEndpoint(app *fiber.Group) {
+	app.Get("/blocks", func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", constants.DEFAULT_BLOCK_HISTORY_LIMIT)
+		if limit <= 0 {
+			return c.Status(400).SendString("invalid limit")
+		}
+		return c.JSON(common.GetBlockHistory(limit))
+	})
+}
@@ -1,77 +1,138 @@
-package core
-
-import (
-	"encoding/json"
-	"log/slog"
-	"sync"
-
-	"github.com/tez-capital/tezpeak/core/common"
-)
-
-type peakStatus struct {
-	Id        string                     `json:"id,omitempty"` // peak instance id
-	Modules   map[string]json.RawMessage `json:"modules,omitempty"`
-	Nodes     map[string]json.RawMessage `json:"nodes,omitempty"`
-	marshaled []byte                     `json:"-"`
-
-	mtx sync.RWMutex `json:"-"`
-}
-
-func newPeakStatus() *peakStatus {
-	return &peakStatus{
-		Id:      "",
-		Modules: make(map[string]json.RawMessage),
-		Nodes:   make(map[string]json.RawMessage),
-		mtx:     sync.RWMutex{},
-	}
-}
-
-func (s *peakStatus) SetId(id string) {
-	s.Id = id
-}
-
-func (s *peakStatus) updateMarshaled() {
-	s.marshaled, _ = json.Marshal(s)
-}
-
-func (s *peakStatus) UpdateModuleStatus(id string, status any) {
-	defer s.updateMarshaled()
-
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	marshaled, err := json.Marshal(status)
-	if err != nil {
-		slog.Error("failed to marshal module status", "error", err.Error())
-		return
-	}
-	s.Modules[id] = marshaled
-}
-
-func (s *peakStatus) UpdateNodeStatus(id string, status common.NodeStatus) {
-	defer s.updateMarshaled()
-
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	marshaled, err := json.Marshal(status)
-	if err != nil {
-		slog.Error("failed to marshal module status", "error", err.Error())
-		return
-	}
-	s.Nodes[id] = marshaled
-}
-
-func (s *peakStatus) String() string {
-	return string(s.marshaled)
-}
-
-type PeakStatusUpdateReportKind string
-
-const (
-	FullStatusUpdated    PeakStatusUpdateReportKind = "full"
-	PartialStatusUpdated PeakStatusUpdateReportKind = "partial"
-)
-
-type PeakStatusUpdatedeRport struct {
-	Id   string     `json:"id,omitempty"` // only for partial
-	Data peakStatus `json:"data,omitempty"`
-}
+package core
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type peakStatus struct {
+	Id        string                     `json:"id,omitempty"` // peak instance id
+	Network   string                     `json:"network,omitempty"`
+	ChainId   string                     `json:"chain_id,omitempty"`
+	Modules   map[string]json.RawMessage `json:"modules,omitempty"`
+	Nodes     map[string]json.RawMessage `json:"nodes,omitempty"`
+	Consensus json.RawMessage            `json:"consensus,omitempty"`
+	Mempool   json.RawMessage            `json:"mempool,omitempty"`
+	Cycle     json.RawMessage            `json:"cycle,omitempty"`
+	marshaled []byte                     `json:"-"`
+
+	mtx sync.RWMutex `json:"-"`
+}
+
+func newPeakStatus() *peakStatus {
+	return &peakStatus{
+		Id:      "",
+		Modules: make(map[string]json.RawMessage),
+		Nodes:   make(map[string]json.RawMessage),
+		mtx:     sync.RWMutex{},
+	}
+}
+
+func (s *peakStatus) SetId(id string) {
+	s.Id = id
+}
+
+func (s *peakStatus) SetNetwork(network *common.NetworkInfo) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.Network = network.Network
+	s.ChainId = network.ChainId
+}
+
+func (s *peakStatus) updateMarshaled() {
+	s.marshaled, _ = json.Marshal(s)
+}
+
+func (s *peakStatus) UpdateModuleStatus(id string, status any) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	marshaled, err := json.Marshal(status)
+	if err != nil {
+		slog.Error("failed to marshal module status", "error", err.Error())
+		return
+	}
+	s.Modules[id] = marshaled
+}
+
+func (s *peakStatus) UpdateNodeStatus(id string, status common.NodeStatus) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	marshaled, err := json.Marshal(status)
+	if err != nil {
+		slog.Error("failed to marshal module status", "error", err.Error())
+		return
+	}
+	s.Nodes[id] = marshaled
+}
+
+func (s *peakStatus) RemoveNodeStatus(id string) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.Nodes, id)
+}
+
+func (s *peakStatus) UpdateConsensus(status common.ConsensusStatus) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	marshaled, err := json.Marshal(status)
+	if err != nil {
+		slog.Error("failed to marshal consensus status", "error", err.Error())
+		return
+	}
+	s.Consensus = marshaled
+}
+
+func (s *peakStatus) UpdateMempool(status common.MempoolStatus) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	marshaled, err := json.Marshal(status)
+	if err != nil {
+		slog.Error("failed to marshal mempool status", "error", err.Error())
+		return
+	}
+	s.Mempool = marshaled
+}
+
+func (s *peakStatus) UpdateCycle(status common.CycleStatus) {
+	defer s.updateMarshaled()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	marshaled, err := json.Marshal(status)
+	if err != nil {
+		slog.Error("failed to marshal cycle status", "error", err.Error())
+		return
+	}
+	s.Cycle = marshaled
+}
+
+func (s *peakStatus) String() string {
+	return string(s.marshaled)
+}
+
+type PeakStatusUpdateReportKind string
+
+const (
+	FullStatusUpdated    PeakStatusUpdateReportKind = "full"
+	PartialStatusUpdated PeakStatusUpdateReportKind = "partial"
+)
+
+type PeakStatusUpdatedeRport struct {
+	Id   string     `json:"id,omitempty"` // only for partial
+	Data peakStatus `json:"data,omitempty"`
+}
@@ -0,0 +1,194 @@
+package common
+
+import (
+	"encoding/binary"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/tezos"
+)
+
+type HistoryBlock struct {
+	Level           int64     `json:"level"`
+	Hash            string    `json:"hash"`
+	Round           int       `json:"round"`
+	PayloadProducer string    `json:"payload_producer"`
+	Proposer        string    `json:"proposer"`
+	Timestamp       time.Time `json:"timestamp"`
+	// milliseconds since the predecessor timestamp, nil if predecessor is not in history
+	InterBlockDelay *int64 `json:"inter_block_delay,omitempty"`
+	// node id -> milliseconds between block timestamp and its arrival to the node
+	ArrivalDelays map[string]int64 `json:"arrival_delays"`
+
+	predecessor string
+}
+
+type BlockHistoryStats struct {
+	Blocks int `json:"blocks"`
+	// average round of blocks
+	AverageRound float64 `json:"average_round"`
+	// percent of blocks baked at round > 0
+	DelayedBlocks float64 `json:"delayed_blocks"`
+}
+
+type BlockHistory struct {
+	Blocks []HistoryBlock     `json:"blocks"`
+	Stats  *BlockHistoryStats `json:"stats"`
+}
+
+// getRound reads round from tenderbake fitness, which is the last 4 byte element
+func getRound(fitness []tezos.HexBytes) int {
+	if len(fitness) == 0 || len(fitness[len(fitness)-1]) != 4 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(fitness[len(fitness)-1]))
+}
+
+// blockHistory keeps blocks seen by block providers for the last levels
+type blockHistory struct {
+	mtx       sync.Mutex
+	blocks    map[string]*HistoryBlock
+	maxLevels int64
+}
+
+var (
+	history = &blockHistory{
+		blocks:    map[string]*HistoryBlock{},
+		maxLevels: constants.BLOCK_HISTORY_LEVELS,
+	}
+)
+
+func (h *blockHistory) observe(nodeId string, block *Block) {
+	if block.LevelInfo == nil {
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	entry, ok := h.blocks[block.Hash]
+	if !ok {
+		entry = &HistoryBlock{
+			Level:           block.LevelInfo.Level,
+			Hash:            block.Hash,
+			Round:           block.Round,
+			PayloadProducer: block.PayloadProducer,
+			Proposer:        block.Proposer,
+			Timestamp:       block.Timestamp,
+			ArrivalDelays:   map[string]int64{},
+			predecessor:     block.Predecessor,
+		}
+		h.blocks[block.Hash] = entry
+		h.prune(entry.Level)
+	}
+	if _, ok := entry.ArrivalDelays[nodeId]; !ok && !block.ReceivedAt.IsZero() {
+		entry.ArrivalDelays[nodeId] = block.ReceivedAt.Sub(block.Timestamp).Milliseconds()
+	}
+}
+
+func (h *blockHistory) prune(level int64) {
+	for hash, block := range h.blocks {
+		if block.Level <= level-h.maxLevels {
+			delete(h.blocks, hash)
+		}
+	}
+}
+
+func (h *blockHistory) head() *HistoryBlock {
+	var head *HistoryBlock
+	for _, block := range h.blocks {
+		if head == nil || block.Level > head.Level || (block.Level == head.Level && block.Round > head.Round) {
+			head = block
+		}
+	}
+	return head
+}
+
+// list returns up to limit blocks of the canonical chain, newest first
+func (h *blockHistory) list(limit int) []HistoryBlock {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	result := []HistoryBlock{}
+	for block := h.head(); block != nil && len(result) < limit; block = h.blocks[block.predecessor] {
+		entry := *block
+		entry.ArrivalDelays = maps.Clone(block.ArrivalDelays)
+		if predecessor, ok := h.blocks[block.predecessor]; ok {
+			delay := block.Timestamp.Sub(predecessor.Timestamp).Milliseconds()
+			entry.InterBlockDelay = &delay
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func getBlockHistoryStats(blocks []HistoryBlock) *BlockHistoryStats {
+	stats := &BlockHistoryStats{Blocks: len(blocks)}
+	if len(blocks) == 0 {
+		return stats
+	}
+	rounds, delayed := 0, 0
+	for _, block := range blocks {
+		rounds += block.Round
+		if block.Round > 0 {
+			delayed++
+		}
+	}
+	stats.AverageRound = float64(rounds) / float64(len(blocks))
+	stats.DelayedBlocks = float64(delayed) * 100 / float64(len(blocks))
+	return stats
+}
+
+// GetBlockHistory returns up to limit latest blocks of the head chain with their stats
+func GetBlockHistory(limit int) BlockHistory {
+	blocks := history.list(min(max(limit, 0), constants.BLOCK_HISTORY_LEVELS))
+	return BlockHistory{
+		Blocks: blocks,
+		Stats:  getBlockHistoryStats(blocks),
+	}
+}
+
+// GetHistoryBlock returns block of the head chain at the level if it is still in history
+func GetHistoryBlock(level int64) (HistoryBlock, bool) {
+	for _, block := range history.list(constants.BLOCK_HISTORY_LEVELS) {
+		if block.Level == level {
+			return block, true
+		}
+	}
+	return HistoryBlock{}, false
+}
+
+// GetClockOffset estimates local clock offset in milliseconds as the smallest arrival delay of recent blocks.
+// Propagation keeps it slightly positive with a precise clock, negative value means the clock is behind.
+func GetClockOffset() (int64, bool) {
+	blocks := history.list(constants.HOST_CLOCK_OFFSET_BLOCKS)
+	offset, ok := int64(0), false
+	for _, block := range blocks {
+		for _, delay := range block.ArrivalDelays {
+			if !ok || delay < offset {
+				offset, ok = delay, true
+			}
+		}
+	}
+	return offset, ok
+}
+
+// getBlockTime estimates block time as median delay between recent blocks
+func getBlockTime() time.Duration {
+	delays := []int64{}
+	for _, block := range history.list(constants.BLOCK_HISTORY_LEVELS) {
+		if block.InterBlockDelay != nil {
+			delays = append(delays, *block.InterBlockDelay)
+		}
+	}
+	if len(delays) == 0 {
+		if _, current, _, ok := GetProtocolConstants(); ok && current.MinimalBlockDelay > 0 {
+			return time.Duration(current.MinimalBlockDelay) * time.Second
+		}
+		return constants.DEFAULT_BLOCK_TIME * time.Second
+	}
+	slices.Sort(delays)
+	return time.Duration(delays[len(delays)/2]) * time.Millisecond
+}
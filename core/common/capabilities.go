@@ -0,0 +1,216 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+// RpcCapability is a family of endpoints public and hardened nodes allow or deny together
+type RpcCapability string
+
+const (
+	MonitorRpcCapability    RpcCapability = "monitor"
+	RightsRpcCapability     RpcCapability = "rights"
+	RawContextRpcCapability RpcCapability = "raw_context"
+	VotesRpcCapability      RpcCapability = "votes"
+	NetworkRpcCapability    RpcCapability = "network"
+	MempoolRpcCapability    RpcCapability = "mempool"
+)
+
+type capabilityProbe struct {
+	path string
+	// streaming endpoints are only opened
+	stream bool
+}
+
+var capabilityProbes = map[RpcCapability]capabilityProbe{
+	MonitorRpcCapability:    {path: "monitor/heads/main", stream: true},
+	RightsRpcCapability:     {path: "chains/main/blocks/head/helpers/baking_rights?max_round=0"},
+	RawContextRpcCapability: {path: "chains/main/blocks/head/context/raw/json/first_level_of_protocol"},
+	VotesRpcCapability:      {path: "chains/main/blocks/head/votes/current_period"},
+	NetworkRpcCapability:    {path: "network/stat"},
+	MempoolRpcCapability:    {path: "chains/main/mempool/monitor_operations", stream: true},
+}
+
+type NodeRole string
+
+const (
+	BlockNodeRole       NodeRole = "block"
+	RightsNodeRole      NodeRole = "rights"
+	GovernanceNodeRole  NodeRole = "governance"
+	NetworkInfoNodeRole NodeRole = "network_info"
+	MempoolNodeRole     NodeRole = "mempool"
+)
+
+// nodeCapabilities holds probed capabilities, capabilities not probed yet are assumed allowed
+type nodeCapabilities struct {
+	mtx     sync.RWMutex
+	allowed map[RpcCapability]bool
+}
+
+func newNodeCapabilities() *nodeCapabilities {
+	return &nodeCapabilities{
+		allowed: map[RpcCapability]bool{},
+	}
+}
+
+func (c *nodeCapabilities) allows(capability RpcCapability) bool {
+	if c == nil {
+		return true
+	}
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	allowed, ok := c.allowed[capability]
+	return !ok || allowed
+}
+
+func (c *nodeCapabilities) set(capability RpcCapability, allowed bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.allowed[capability] = allowed
+}
+
+func (c *nodeCapabilities) snapshot() map[RpcCapability]bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return maps.Clone(c.allowed)
+}
+
+// Allows reports whether the node serves the endpoint family, unknown capabilities are allowed
+func (n *ActiveRpcNode) Allows(capability RpcCapability) bool {
+	return n.capabilities.allows(capability)
+}
+
+// Roles returns configured roles the node can actually serve
+func (n *ActiveRpcNode) Roles() []NodeRole {
+	return getNodeRoles(n.TezosNode, n.capabilities)
+}
+
+func getNodeRoles(node configuration.TezosNode, capabilities *nodeCapabilities) []NodeRole {
+	roles := []NodeRole{}
+	if node.IsBlockProvider && capabilities.allows(MonitorRpcCapability) {
+		roles = append(roles, BlockNodeRole)
+	}
+	if node.IsRightsProvider && capabilities.allows(RightsRpcCapability) {
+		roles = append(roles, RightsNodeRole)
+	}
+	if node.IsGovernanceProvider && capabilities.allows(VotesRpcCapability) {
+		roles = append(roles, GovernanceNodeRole)
+	}
+	if node.IsNetworkInfoProvider && capabilities.allows(NetworkRpcCapability) {
+		roles = append(roles, NetworkInfoNodeRole)
+	}
+	if node.IsBlockProvider && capabilities.allows(MempoolRpcCapability) {
+		roles = append(roles, MempoolNodeRole)
+	}
+	return roles
+}
+
+// isDenied reports whether the node answered it does not serve the endpoint
+func isDenied(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500
+}
+
+// probeStream opens the stream and closes it once the node answered
+func probeStream(ctx context.Context, node *poolNode, path string) (int, error) {
+	address := strings.TrimSuffix(node.Address, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := newInstrumentedHttpClient(node.metrics, 0).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// probeCapability returns whether the capability is allowed, false ok means the node did not answer
+func (n *poolNode) probeCapability(ctx context.Context, probe capabilityProbe) (allowed bool, ok bool) {
+	probeCtx, cancel := context.WithTimeout(ctx, constants.NODE_CAPABILITY_PROBE_TIMEOUT*time.Second)
+	defer cancel()
+
+	if probe.stream {
+		statusCode, err := probeStream(probeCtx, n, probe.path)
+		if err != nil {
+			return false, false
+		}
+		return !isDenied(statusCode), statusCode < 500
+	}
+
+	var result json.RawMessage
+	err := n.Client.Get(probeCtx, probe.path, &result)
+	var rpcError rpc.RPCError
+	switch {
+	case err == nil:
+		return true, true
+	case errors.As(err, &rpcError) && isDenied(rpcError.StatusCode()):
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// probeCapabilities probes all capabilities, returns false if some remain unknown
+func (n *poolNode) probeCapabilities(ctx context.Context) bool {
+	type result struct {
+		capability RpcCapability
+		allowed    bool
+		ok         bool
+	}
+	results := make(chan result, len(capabilityProbes))
+	for capability, probe := range capabilityProbes {
+		go func() {
+			allowed, ok := n.probeCapability(ctx, probe)
+			results <- result{capability: capability, allowed: allowed, ok: ok}
+		}()
+	}
+
+	complete := true
+	for range capabilityProbes {
+		result := <-results
+		if !result.ok {
+			complete = false
+			continue
+		}
+		if !result.allowed && n.capabilities.allows(result.capability) {
+			slog.Info("node does not allow endpoints", "id", n.id, "source", n.Address, "capability", result.capability)
+		}
+		n.capabilities.set(result.capability, result.allowed)
+	}
+	return complete
+}
+
+// runCapabilityProbes probes the node until every capability is known
+func (n *poolNode) runCapabilityProbes(ctx context.Context) {
+	for {
+		complete := n.probeCapabilities(ctx)
+		capabilities := n.capabilities.snapshot()
+		roles := n.Roles()
+		n.reporter.update(func(s *NodeStatus) {
+			s.Capabilities = capabilities
+			s.Roles = roles
+		})
+		if complete {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.NODE_CAPABILITY_RETRY_INTERVAL * time.Second):
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"bytes"
+	"cmp"
+	"log/slog"
+	"sync"
+
+	"github.com/trilitech/tzgo/rpc"
+	"github.com/trilitech/tzgo/tezos"
+)
+
+type BlockRef struct {
+	Level int64  `json:"level"`
+	Hash  string `json:"hash"`
+}
+
+// ReorgEvent is emitted when blocks of already announced levels were replaced,
+// e.g. round 1 block replacing round 0 proposal or a short reorganization
+type ReorgEvent struct {
+	// lowest level whose block changed
+	Level     int64      `json:"level"`
+	OldBranch []BlockRef `json:"old_branch"`
+	NewBranch []BlockRef `json:"new_branch"`
+}
+
+type chainBlock struct {
+	hash        string
+	predecessor string
+	fitness     []tezos.HexBytes
+}
+
+// chainWindow tracks hashes of the head chain for the last few levels
+type chainWindow struct {
+	mtx       sync.Mutex
+	head      int64
+	blocks    map[int64]chainBlock
+	onReorg   func(*ReorgEvent)
+	maxLevels int64
+}
+
+func newChainWindow(maxLevels int64, onReorg func(*ReorgEvent)) *chainWindow {
+	return &chainWindow{
+		blocks:    map[int64]chainBlock{},
+		onReorg:   onReorg,
+		maxLevels: maxLevels,
+	}
+}
+
+// compareFitness compares fitness like octez - longer fitness is greater, then element by element
+// where longer element is greater
+func compareFitness(a, b []tezos.HexBytes) int {
+	if c := cmp.Compare(len(a), len(b)); c != 0 {
+		return c
+	}
+	for i := range a {
+		if c := cmp.Compare(len(a[i]), len(b[i])); c != 0 {
+			return c
+		}
+		if c := bytes.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// branchFrom returns blocks of the window from level up to the head
+func (w *chainWindow) branchFrom(level int64) []BlockRef {
+	branch := []BlockRef{}
+	for l := level; l <= w.head; l++ {
+		if block, ok := w.blocks[l]; ok {
+			branch = append(branch, BlockRef{Level: l, Hash: block.hash})
+		}
+	}
+	return branch
+}
+
+// add records the header and reports whether it is a new head level (to be announced as new block).
+// Blocks replacing already known levels are announced through onReorg instead.
+func (w *chainWindow) add(h *rpc.BlockHeaderLogEntry) bool {
+	return w.addBlock(h.Level, chainBlock{
+		hash:        h.Hash.String(),
+		predecessor: h.Predecessor.String(),
+		fitness:     h.Fitness,
+	})
+}
+
+func (w *chainWindow) addBlock(level int64, block chainBlock) bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if known, ok := w.blocks[level]; ok && known.hash == block.hash {
+		return false // seen already, e.g. from other node
+	}
+
+	newHead := level > w.head
+	if !newHead {
+		// same or lower level replaces the head only if it has greater fitness
+		if head, ok := w.blocks[w.head]; ok && compareFitness(block.fitness, head.fitness) <= 0 {
+			return false
+		}
+	}
+
+	// lowest changed level, predecessor of the new block may differ too
+	reorgLevel := int64(0)
+	if !newHead {
+		reorgLevel = level
+	}
+	if previous, ok := w.blocks[level-1]; ok && block.predecessor != "" && previous.hash != block.predecessor {
+		reorgLevel = level - 1
+	}
+
+	var event *ReorgEvent
+	if reorgLevel > 0 {
+		event = &ReorgEvent{
+			Level:     reorgLevel,
+			OldBranch: w.branchFrom(reorgLevel),
+			NewBranch: []BlockRef{},
+		}
+		for l := range w.blocks {
+			if l >= reorgLevel {
+				delete(w.blocks, l)
+			}
+		}
+		if reorgLevel < level {
+			w.blocks[level-1] = chainBlock{hash: block.predecessor}
+		}
+	}
+
+	w.blocks[level] = block
+	w.head = level
+	for l := range w.blocks {
+		if l <= w.head-w.maxLevels {
+			delete(w.blocks, l)
+		}
+	}
+
+	if event != nil {
+		event.NewBranch = w.branchFrom(reorgLevel)
+		slog.Info("chain reorganization", "level", event.Level, "old_branch", event.OldBranch, "new_branch", event.NewBranch)
+		w.onReorg(event)
+	}
+	return newHead
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trilitech/tzgo/tezos"
+)
+
+// fitness of a tenderbake block at the round
+func testFitness(level byte, round byte) []tezos.HexBytes {
+	return []tezos.HexBytes{{0x02}, {0, 0, 0, level}, {}, {0xff, 0xff, 0xff, 0xff}, {0, 0, 0, round}}
+}
+
+func TestCompareFitness(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []tezos.HexBytes
+		expected int
+	}{
+		{"equal", testFitness(10, 0), testFitness(10, 0), 0},
+		{"higher round", testFitness(10, 1), testFitness(10, 0), 1},
+		{"higher level", testFitness(10, 0), testFitness(11, 0), -1},
+		{"longer element", []tezos.HexBytes{{0x02}, {1, 0}}, []tezos.HexBytes{{0x02}, {0xff}}, 1},
+		{"longer fitness first", []tezos.HexBytes{{0x01}, {0, 0, 0, 1}}, testFitness(1, 0), -1},
+		{"longer fitness with smaller elements", testFitness(1, 0), []tezos.HexBytes{{0x02}, {0, 0, 0, 9}, {}, {0xff}}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if c := compareFitness(test.a, test.b); c != test.expected {
+				t.Errorf("compareFitness = %d, expected %d", c, test.expected)
+			}
+		})
+	}
+}
+
+func TestChainWindowAdd(t *testing.T) {
+	type step struct {
+		level   int64
+		block   chainBlock
+		newHead bool
+		reorg   *ReorgEvent
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "new heads",
+			steps: []step{
+				{level: 10, block: chainBlock{hash: "A10", predecessor: "A9", fitness: testFitness(10, 0)}, newHead: true},
+				{level: 11, block: chainBlock{hash: "A11", predecessor: "A10", fitness: testFitness(11, 0)}, newHead: true},
+				// same block from another node
+				{level: 11, block: chainBlock{hash: "A11", predecessor: "A10", fitness: testFitness(11, 0)}, newHead: false},
+			},
+		},
+		{
+			name: "same level round replacement",
+			steps: []step{
+				{level: 10, block: chainBlock{hash: "A10", predecessor: "A9", fitness: testFitness(10, 0)}, newHead: true},
+				{level: 11, block: chainBlock{hash: "A11", predecessor: "A10", fitness: testFitness(11, 0)}, newHead: true},
+				{
+					level:   11,
+					block:   chainBlock{hash: "B11", predecessor: "A10", fitness: testFitness(11, 1)},
+					newHead: false,
+					reorg: &ReorgEvent{
+						Level:     11,
+						OldBranch: []BlockRef{{Level: 11, Hash: "A11"}},
+						NewBranch: []BlockRef{{Level: 11, Hash: "B11"}},
+					},
+				},
+				// lower round does not replace the head
+				{level: 11, block: chainBlock{hash: "C11", predecessor: "A10", fitness: testFitness(11, 0)}, newHead: false},
+				{level: 12, block: chainBlock{hash: "B12", predecessor: "B11", fitness: testFitness(12, 0)}, newHead: true},
+			},
+		},
+		{
+			name: "predecessor mismatch",
+			steps: []step{
+				{level: 10, block: chainBlock{hash: "A10", predecessor: "A9", fitness: testFitness(10, 0)}, newHead: true},
+				{level: 11, block: chainBlock{hash: "A11", predecessor: "A10", fitness: testFitness(11, 0)}, newHead: true},
+				{
+					level:   12,
+					block:   chainBlock{hash: "B12", predecessor: "B11", fitness: testFitness(12, 0)},
+					newHead: true,
+					reorg: &ReorgEvent{
+						Level:     11,
+						OldBranch: []BlockRef{{Level: 11, Hash: "A11"}},
+						NewBranch: []BlockRef{{Level: 11, Hash: "B11"}, {Level: 12, Hash: "B12"}},
+					},
+				},
+				{level: 13, block: chainBlock{hash: "B13", predecessor: "B12", fitness: testFitness(13, 0)}, newHead: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var reorg *ReorgEvent
+			window := newChainWindow(10, func(event *ReorgEvent) { reorg = event })
+			for i, step := range test.steps {
+				reorg = nil
+				if newHead := window.addBlock(step.level, step.block); newHead != step.newHead {
+					t.Errorf("step %d: new head = %v, expected %v", i, newHead, step.newHead)
+				}
+				if !reflect.DeepEqual(reorg, step.reorg) {
+					t.Errorf("step %d: reorg = %+v, expected %+v", i, reorg, step.reorg)
+				}
+			}
+		})
+	}
+}
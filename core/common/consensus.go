@@ -0,0 +1,247 @@
+package common
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+// NodeConsensusStatus is the position of the node head relative to the network head
+type NodeConsensusStatus struct {
+	LagLevels         int64 `json:"lag_levels"`
+	LagSeconds        int64 `json:"lag_seconds"`
+	OnCanonicalBranch bool  `json:"on_canonical_branch"`
+}
+
+type NetworkHead struct {
+	Level     int64     `json:"level"`
+	Hash      string    `json:"hash"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Fork lists branches (block hash -> node ids) nodes disagree on at the level
+type Fork struct {
+	Level    int64               `json:"level"`
+	Branches map[string][]string `json:"branches"`
+}
+
+type ConsensusStatus struct {
+	NetworkHead *NetworkHead `json:"network_head"`
+	Forks       []Fork       `json:"forks,omitempty"`
+}
+
+type ConsensusStatusUpdate struct {
+	Status ConsensusStatus
+}
+
+func (s *ConsensusStatusUpdate) GetId() string {
+	return "consensus"
+}
+
+func (s *ConsensusStatusUpdate) GetData() any {
+	return s.Status
+}
+
+type nodeHead struct {
+	level       int64
+	hash        string
+	predecessor string
+	timestamp   time.Time
+}
+
+type consensusTracker struct {
+	mtx          sync.Mutex
+	heads        map[string]nodeHead
+	predecessors map[string]string
+	levels       map[string]int64 // block hash -> level
+	statuses     map[string]NodeConsensusStatus
+	forks        map[int64]struct{}
+
+	statusChannel chan<- StatusUpdate
+}
+
+var (
+	consensus = &consensusTracker{
+		heads:        map[string]nodeHead{},
+		predecessors: map[string]string{},
+		levels:       map[string]int64{},
+		statuses:     map[string]NodeConsensusStatus{},
+		forks:        map[int64]struct{}{},
+	}
+)
+
+func (t *consensusTracker) setStatusChannel(statusChannel chan<- StatusUpdate) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.statusChannel = statusChannel
+}
+
+func (t *consensusTracker) forget(nodeId string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	delete(t.heads, nodeId)
+	delete(t.statuses, nodeId)
+}
+
+// prune forgets blocks which are out of the window below the network head
+func (t *consensusTracker) prune(headLevel int64) {
+	for hash, level := range t.levels {
+		if level < headLevel-constants.CONSENSUS_LEVEL_WINDOW {
+			delete(t.levels, hash)
+			delete(t.predecessors, hash)
+		}
+	}
+}
+
+// networkHead picks the head supported by most nodes, a node supports heads which equal or descend from its head.
+// A node running ahead on a fork is supported only by itself and nodes behind the fork point.
+func (t *consensusTracker) networkHead() *nodeHead {
+	var result *nodeHead
+	resultSupport := 0
+	for _, head := range t.heads {
+		chain := t.canonicalChain(&head)
+		support := 0
+		for _, other := range t.heads {
+			if chain[other.level] == other.hash {
+				support++
+			}
+		}
+		switch {
+		case result == nil || support > resultSupport:
+		case support < resultSupport:
+			continue
+		// highest of equally supported heads wins, hash breaks ties to stay deterministic
+		case head.level > result.level:
+		case head.level == result.level && head.hash < result.hash:
+		default:
+			continue
+		}
+		result = &head
+		resultSupport = support
+	}
+	return result
+}
+
+// canonicalChain walks predecessors from the network head, returns level -> hash
+func (t *consensusTracker) canonicalChain(head *nodeHead) map[int64]string {
+	chain := map[int64]string{}
+	hash, level := head.hash, head.level
+	for hash != "" {
+		chain[level] = hash
+		hash = t.predecessors[hash]
+		level--
+	}
+	return chain
+}
+
+func (t *consensusTracker) detectForks(canonical map[int64]string, statuses map[string]NodeConsensusStatus) []Fork {
+	forks := map[int64]map[string][]string{}
+	addBranch := func(level int64, hash string, nodeId string) {
+		if _, ok := forks[level]; !ok {
+			forks[level] = map[string][]string{}
+		}
+		if !slices.Contains(forks[level][hash], nodeId) {
+			forks[level][hash] = append(forks[level][hash], nodeId)
+		}
+	}
+
+	for nodeId, head := range t.heads {
+		if hash, ok := canonical[head.level]; ok && hash != head.hash {
+			// same level, different hash
+			addBranch(head.level, head.hash, nodeId)
+			addBranch(head.level, hash, "")
+		}
+		if hash, ok := canonical[head.level-1]; ok && head.predecessor != "" && hash != head.predecessor {
+			// same hash chain level, different predecessor
+			addBranch(head.level-1, head.predecessor, nodeId)
+			addBranch(head.level-1, hash, "")
+		}
+	}
+
+	result := make([]Fork, 0, len(forks))
+	for level, branches := range forks {
+		// nodes following the canonical chain at the level
+		for nodeId, head := range t.heads {
+			if statuses[nodeId].OnCanonicalBranch && head.level >= level {
+				addBranch(level, canonical[level], nodeId)
+			}
+		}
+		for hash, nodes := range branches {
+			branches[hash] = lo.Without(nodes, "")
+			slices.Sort(branches[hash])
+		}
+		result = append(result, Fork{Level: level, Branches: branches})
+	}
+	slices.SortFunc(result, func(a, b Fork) int { return cmp.Compare(a.Level, b.Level) })
+	return result
+}
+
+// observe records new head of the node and returns its consensus status,
+// nodes whose status changed are reported through their reporters
+func (t *consensusTracker) observe(nodeId string, head nodeHead) NodeConsensusStatus {
+	t.mtx.Lock()
+	t.heads[nodeId] = head
+	t.predecessors[head.hash] = head.predecessor
+	t.levels[head.hash] = head.level
+
+	networkHead := t.networkHead()
+	t.prune(networkHead.level)
+	canonical := t.canonicalChain(networkHead)
+
+	changed := map[string]NodeConsensusStatus{}
+	statuses := map[string]NodeConsensusStatus{}
+	for id, nodeHead := range t.heads {
+		status := NodeConsensusStatus{
+			LagLevels:         max(networkHead.level-nodeHead.level, 0),
+			LagSeconds:        max(int64(networkHead.timestamp.Sub(nodeHead.timestamp).Seconds()), 0),
+			OnCanonicalBranch: true,
+		}
+		if hash, ok := canonical[nodeHead.level]; ok {
+			status.OnCanonicalBranch = hash == nodeHead.hash
+		}
+		statuses[id] = status
+		if previous, ok := t.statuses[id]; id != nodeId && (!ok || previous != status) {
+			changed[id] = status
+		}
+	}
+	t.statuses = statuses
+
+	forks := t.detectForks(canonical, statuses)
+	detected := map[int64]struct{}{}
+	for _, fork := range forks {
+		detected[fork.Level] = struct{}{}
+		if _, ok := t.forks[fork.Level]; !ok {
+			slog.Warn("fork detected, nodes disagree on block", "level", fork.Level, "branches", fork.Branches)
+		}
+	}
+	t.forks = detected
+	statusChannel := t.statusChannel
+	t.mtx.Unlock()
+
+	for id, status := range changed {
+		if node, ok := pool.get(id); ok {
+			node.reporter.update(func(s *NodeStatus) {
+				s.Consensus = &status
+			})
+		}
+	}
+
+	if statusChannel != nil {
+		statusChannel <- &ConsensusStatusUpdate{
+			Status: ConsensusStatus{
+				NetworkHead: &NetworkHead{
+					Level:     networkHead.level,
+					Hash:      networkHead.hash,
+					Timestamp: networkHead.timestamp,
+				},
+				Forks: forks,
+			},
+		}
+	}
+	return statuses[nodeId]
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+type CycleEventKind string
+
+const (
+	NewCycleEvent    CycleEventKind = "new_cycle"
+	CycleEndingEvent CycleEventKind = "cycle_ending"
+)
+
+type CycleEvent struct {
+	Kind       CycleEventKind `json:"kind"`
+	Cycle      int64          `json:"cycle"`
+	FirstLevel int64          `json:"first_level"`
+	LastLevel  int64          `json:"last_level"`
+	// blocks until the end of the cycle at the time of the event
+	RemainingBlocks int64              `json:"remaining_blocks"`
+	Constants       *ProtocolConstants `json:"constants"`
+}
+
+type CycleStatus struct {
+	Cycle      int64 `json:"cycle"`
+	FirstLevel int64 `json:"first_level"`
+	LastLevel  int64 `json:"last_level"`
+	Level      int64 `json:"level"`
+	// percent of the cycle baked
+	Progress        float64 `json:"progress"`
+	RemainingBlocks int64   `json:"remaining_blocks"`
+	// estimated end of the cycle assuming blocks at round 0
+	Eta time.Time `json:"eta"`
+}
+
+type CycleStatusUpdate struct {
+	Status CycleStatus
+}
+
+func (s *CycleStatusUpdate) GetId() string {
+	return "cycle"
+}
+
+func (s *CycleStatusUpdate) GetData() any {
+	return s.Status
+}
+
+// CycleEventSource emits cycle events derived from blocks of block providers
+type CycleEventSource struct {
+	*EventSource[*CycleEvent]
+
+	mtx            sync.Mutex
+	level          int64
+	cycle          int64
+	firstLevel     int64
+	blocksPerCycle int64
+	endingEmitted  int64
+	endingBlocks   int64
+	statusChannel  chan<- StatusUpdate
+}
+
+func NewCycleEventSource() *CycleEventSource {
+	return &CycleEventSource{
+		EventSource:   NewEventSource[*CycleEvent](nil),
+		cycle:         -1,
+		endingEmitted: -1,
+		endingBlocks:  constants.DEFAULT_CYCLE_ENDING_BLOCKS,
+	}
+}
+
+func (es *CycleEventSource) getConstants(block *Block) (*ProtocolConstants, error) {
+	return protocolConstants.get(context.Background(), block.Protocol, block.Hash)
+}
+
+func (es *CycleEventSource) setStatusChannel(statusChannel chan<- StatusUpdate) {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+	es.statusChannel = statusChannel
+}
+
+// observe processes block of a block provider, each level is processed once
+func (es *CycleEventSource) observe(block *Block) {
+	if block.LevelInfo == nil {
+		return
+	}
+	es.mtx.Lock()
+	if block.LevelInfo.Level <= es.level {
+		es.mtx.Unlock()
+		return
+	}
+	es.level = block.LevelInfo.Level
+	es.mtx.Unlock()
+
+	protocolConstants, err := es.getConstants(block)
+	if err != nil || protocolConstants.BlocksPerCycle <= 0 {
+		slog.Debug("failed to get protocol constants", "level", block.LevelInfo.Level, "error", err)
+		return
+	}
+
+	level := block.LevelInfo.Level
+	firstLevel := level - block.LevelInfo.CyclePosition
+	lastLevel := firstLevel + protocolConstants.BlocksPerCycle - 1
+	remaining := lastLevel - level
+	newEvent := func(kind CycleEventKind) *CycleEvent {
+		return &CycleEvent{
+			Kind:            kind,
+			Cycle:           block.LevelInfo.Cycle,
+			FirstLevel:      firstLevel,
+			LastLevel:       lastLevel,
+			RemainingBlocks: remaining,
+			Constants:       protocolConstants,
+		}
+	}
+
+	es.mtx.Lock()
+	events := []*CycleEvent{}
+	if es.cycle != -1 && block.LevelInfo.Cycle > es.cycle {
+		events = append(events, newEvent(NewCycleEvent))
+	}
+	es.cycle = block.LevelInfo.Cycle
+	es.firstLevel = firstLevel
+	es.blocksPerCycle = protocolConstants.BlocksPerCycle
+	if remaining <= es.endingBlocks && es.endingEmitted < block.LevelInfo.Cycle {
+		es.endingEmitted = block.LevelInfo.Cycle
+		events = append(events, newEvent(CycleEndingEvent))
+	}
+	statusChannel := es.statusChannel
+	es.mtx.Unlock()
+
+	for _, event := range events {
+		slog.Debug("cycle event", "kind", event.Kind, "cycle", event.Cycle, "remaining_blocks", event.RemainingBlocks)
+		es.source <- event
+	}
+
+	if statusChannel != nil {
+		statusChannel <- &CycleStatusUpdate{Status: CycleStatus{
+			Cycle:           block.LevelInfo.Cycle,
+			FirstLevel:      firstLevel,
+			LastLevel:       lastLevel,
+			Level:           level,
+			Progress:        float64(block.LevelInfo.CyclePosition+1) * 100 / float64(protocolConstants.BlocksPerCycle),
+			RemainingBlocks: remaining,
+			Eta:             estimateTime(block.Timestamp, remaining, protocolConstants),
+		}}
+	}
+}
+
+var (
+	cycleEventSource = NewCycleEventSource()
+)
+
+func init() {
+	go cycleEventSource.Run()
+}
+
+// SetCycleEndingBlocks sets how many blocks before the end of cycle the cycle ending event is emitted
+func SetCycleEndingBlocks(blocks int64) {
+	cycleEventSource.mtx.Lock()
+	defer cycleEventSource.mtx.Unlock()
+	if blocks > 0 {
+		cycleEventSource.endingBlocks = blocks
+	}
+}
+
+// GetCycleOfLevel returns cycle of the level and first level of the cycle derived from the last observed cycle,
+// false until a cycle was observed
+func GetCycleOfLevel(level int64) (int64, int64, bool) {
+	cycleEventSource.mtx.Lock()
+	defer cycleEventSource.mtx.Unlock()
+	es := cycleEventSource
+	if es.cycle < 0 || es.blocksPerCycle <= 0 {
+		return 0, 0, false
+	}
+	offset := level - es.firstLevel
+	cycles := offset / es.blocksPerCycle
+	if offset < 0 && offset%es.blocksPerCycle != 0 {
+		cycles-- // floor
+	}
+	return es.cycle + cycles, es.firstLevel + cycles*es.blocksPerCycle, true
+}
+
+// GetCycleLevels returns first and last level of the cycle derived from the last observed cycle
+func GetCycleLevels(cycle int64) (int64, int64, bool) {
+	cycleEventSource.mtx.Lock()
+	defer cycleEventSource.mtx.Unlock()
+	es := cycleEventSource
+	if es.cycle < 0 || es.blocksPerCycle <= 0 {
+		return 0, 0, false
+	}
+	firstLevel := es.firstLevel + (cycle-es.cycle)*es.blocksPerCycle
+	return firstLevel, firstLevel + es.blocksPerCycle - 1, true
+}
+
+func SubscribeToCycleEvents() (uuid.UUID, <-chan *CycleEvent, error) {
+	return cycleEventSource.Subscribe()
+}
+
+func UnsubscribeFromCycleEvents(id uuid.UUID) {
+	cycleEventSource.Unsubscribe(id)
+}
@@ -0,0 +1,274 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+type MempoolClass string
+
+const (
+	MempoolApplied       MempoolClass = "applied"
+	MempoolBranchDelayed MempoolClass = "branch_delayed"
+	MempoolRefused       MempoolClass = "refused"
+)
+
+// query of monitor_operations streaming only operations of the class
+var mempoolClassQueries = map[MempoolClass]string{
+	MempoolApplied:       "validated=true&branch_delayed=false&branch_refused=false&refused=false&outdated=false",
+	MempoolBranchDelayed: "validated=false&branch_delayed=true&branch_refused=false&refused=false&outdated=false",
+	MempoolRefused:       "validated=false&branch_delayed=false&branch_refused=true&refused=true&outdated=false",
+}
+
+type MempoolOperation struct {
+	Hash   string       `json:"hash"`
+	Kind   string       `json:"kind"`
+	Source string       `json:"source"`
+	Class  MempoolClass `json:"class"`
+	Error  string       `json:"error,omitempty"`
+	SeenAt time.Time    `json:"seen_at"`
+}
+
+type MempoolStatus struct {
+	// operations seen per class since start
+	Counters map[MempoolClass]int `json:"counters"`
+	// most recent tracked operations
+	Recent  []MempoolOperation `json:"recent"`
+	Refused []MempoolOperation `json:"refused"`
+}
+
+type MempoolStatusUpdate struct {
+	Status MempoolStatus
+}
+
+func (s *MempoolStatusUpdate) GetId() string {
+	return "mempool"
+}
+
+func (s *MempoolStatusUpdate) GetData() any {
+	return s.Status
+}
+
+type MempoolSources struct {
+	// consensus operations are matched to bakers through attestation slots
+	Bakers  []string
+	Wallets []string
+}
+
+type mempoolOperationContent struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Slot   *int   `json:"slot"`
+	Level  int64  `json:"level"`
+}
+
+type mempoolOperationError struct {
+	Id string `json:"id"`
+}
+
+type mempoolOperation struct {
+	Hash     string                    `json:"hash"`
+	Contents []mempoolOperationContent `json:"contents"`
+	Error    []mempoolOperationError   `json:"error"`
+}
+
+type seenOperation struct {
+	class MempoolClass
+	at    time.Time
+}
+
+type attestationSlots struct {
+	Delegates []struct {
+		Delegate  string `json:"delegate"`
+		FirstSlot int    `json:"first_slot"`
+	} `json:"delegates"`
+}
+
+type mempoolMonitor struct {
+	sources       MempoolSources
+	statusChannel chan<- StatusUpdate
+
+	mtx    sync.Mutex
+	seen   map[string]seenOperation
+	status MempoolStatus
+	// level -> slot -> baker
+	slots map[int64]map[int]string
+}
+
+func (m *mempoolMonitor) getSlots(ctx context.Context, level int64) map[int]string {
+	m.mtx.Lock()
+	slots, ok := m.slots[level]
+	m.mtx.Unlock()
+	if ok {
+		return slots
+	}
+
+	query := url.Values{"level": {fmt.Sprint(level)}, "delegate": m.sources.Bakers}
+	rights, err := AttemptWithRpcClients(ctx, func(node *ActiveRpcNode) ([]attestationSlots, error) {
+		if !node.IsRightsProvider || !node.Allows(RightsRpcCapability) {
+			return nil, fmt.Errorf("%w: not a rights provider", constants.ErrNodeNotApplicable)
+		}
+		var rights []attestationSlots
+		err := node.Get(ctx, "chains/main/blocks/head/helpers/attestation_rights?"+query.Encode(), &rights)
+		return rights, err
+	})
+	if err != nil {
+		slog.Debug("failed to get attestation slots", "level", level, "error", err.Error())
+		return nil
+	}
+
+	slots = map[int]string{}
+	for _, right := range rights {
+		for _, delegate := range right.Delegates {
+			slots[delegate.FirstSlot] = delegate.Delegate
+		}
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for cached := range m.slots {
+		if cached < level-constants.MEMPOOL_SLOTS_CACHE_LEVELS {
+			delete(m.slots, cached)
+		}
+	}
+	m.slots[level] = slots
+	return slots
+}
+
+// getSource returns tracked source of the operation, empty if not tracked
+func (m *mempoolMonitor) getSource(ctx context.Context, op *mempoolOperation) (string, string) {
+	for _, content := range op.Contents {
+		if content.Source != "" {
+			if slices.Contains(m.sources.Bakers, content.Source) || slices.Contains(m.sources.Wallets, content.Source) {
+				return content.Source, content.Kind
+			}
+			continue
+		}
+		if content.Slot != nil && len(m.sources.Bakers) > 0 {
+			if baker, ok := m.getSlots(ctx, content.Level)[*content.Slot]; ok {
+				return baker, content.Kind
+			}
+		}
+	}
+	return "", ""
+}
+
+func (m *mempoolMonitor) track(ctx context.Context, op *mempoolOperation, class MempoolClass) {
+	m.mtx.Lock()
+	known, ok := m.seen[op.Hash]
+	m.mtx.Unlock()
+	if ok && known.class == class {
+		return // streams are reopened on every block and repeat operations
+	}
+
+	source, kind := m.getSource(ctx, op)
+	if source == "" {
+		return
+	}
+
+	operation := MempoolOperation{
+		Hash:   op.Hash,
+		Kind:   kind,
+		Source: source,
+		Class:  class,
+		Error:  strings.Join(lo.Map(op.Error, func(e mempoolOperationError, _ int) string { return e.Id }), ", "),
+		SeenAt: time.Now(),
+	}
+	if class == MempoolRefused {
+		slog.Warn("operation refused by mempool", "hash", operation.Hash, "kind", operation.Kind, "source", operation.Source, "error", operation.Error)
+	}
+
+	m.mtx.Lock()
+	for hash, seen := range m.seen {
+		if time.Since(seen.at) > constants.MEMPOOL_SEEN_OPERATION_TTL*time.Second {
+			delete(m.seen, hash)
+		}
+	}
+	m.seen[op.Hash] = seenOperation{class: class, at: operation.SeenAt}
+	m.status.Counters[class]++
+	m.status.Recent = slices.DeleteFunc(m.status.Recent, func(o MempoolOperation) bool { return o.Hash == op.Hash })
+	m.status.Recent = append([]MempoolOperation{operation}, m.status.Recent...)
+	m.status.Recent = m.status.Recent[:min(len(m.status.Recent), constants.MEMPOOL_RECENT_OPERATIONS)]
+	if class == MempoolRefused {
+		m.status.Refused = append([]MempoolOperation{operation}, m.status.Refused...)
+		m.status.Refused = m.status.Refused[:min(len(m.status.Refused), constants.MEMPOOL_RECENT_OPERATIONS)]
+	}
+	status := MempoolStatus{
+		Counters: maps.Clone(m.status.Counters),
+		Recent:   slices.Clone(m.status.Recent),
+		Refused:  slices.Clone(m.status.Refused),
+	}
+	m.mtx.Unlock()
+
+	m.statusChannel <- &MempoolStatusUpdate{Status: status}
+}
+
+func getMempoolNode() (*poolNode, bool) {
+	for _, node := range pool.candidates() {
+		if node.IsBlockProvider && node.Allows(MempoolRpcCapability) {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// streamClass reads operations of the class until the node closes the stream, which happens on every new head
+func (m *mempoolMonitor) streamClass(ctx context.Context, node *poolNode, class MempoolClass) error {
+	return streamRpc(ctx, node, "chains/main/mempool/monitor_operations?"+mempoolClassQueries[class], func(ops []mempoolOperation) {
+		for i := range ops {
+			m.track(ctx, &ops[i], class)
+		}
+	})
+}
+
+func (m *mempoolMonitor) runClass(ctx context.Context, class MempoolClass) {
+	for {
+		node, ok := getMempoolNode()
+		var err error
+		if ok {
+			err = m.streamClass(ctx, node, class)
+		} else {
+			err = constants.ErrNoAvailableNode
+		}
+		if err != nil {
+			slog.Debug("mempool monitor disconnected", "class", class, "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(lo.Ternary(err == nil, time.Duration(0), constants.MEMPOOL_MONITOR_RETRY_INTERVAL*time.Second)):
+		}
+	}
+}
+
+// StartMempoolMonitor tracks mempool operations of bakers and wallets through a block provider node
+func StartMempoolMonitor(ctx context.Context, sources MempoolSources, statusChannel chan<- StatusUpdate) {
+	if len(sources.Bakers) == 0 && len(sources.Wallets) == 0 {
+		return
+	}
+
+	monitor := &mempoolMonitor{
+		sources:       sources,
+		statusChannel: statusChannel,
+		seen:          map[string]seenOperation{},
+		status: MempoolStatus{
+			Counters: map[MempoolClass]int{},
+			Recent:   []MempoolOperation{},
+			Refused:  []MempoolOperation{},
+		},
+		slots: map[int64]map[int]string{},
+	}
+	for class := range mempoolClassQueries {
+		go monitor.runClass(ctx, class)
+	}
+}
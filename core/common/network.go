@@ -0,0 +1,219 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+type NetworkInfo struct {
+	Network string `json:"network"`
+	ChainId string `json:"chain_id"`
+}
+
+// NetworkStatusUpdate is sent when the network is detected after start
+type NetworkStatusUpdate struct {
+	Network NetworkInfo
+}
+
+func (s *NetworkStatusUpdate) GetId() string {
+	return "network"
+}
+
+func (s *NetworkStatusUpdate) GetData() any {
+	return s.Network
+}
+
+var (
+	networkMtx    sync.RWMutex
+	activeNetwork *NetworkInfo
+	// closed once chain id of the network is known
+	networkResolved = make(chan struct{})
+)
+
+// GetNetworkInfo returns network tezpeak operates on, nil before ResolveNetwork.
+// Chain id is empty until the network is detected.
+func GetNetworkInfo() *NetworkInfo {
+	networkMtx.RLock()
+	defer networkMtx.RUnlock()
+	return activeNetwork
+}
+
+// WaitForNetwork waits until chain id of the network is known, returns false if the context is done first
+func WaitForNetwork(ctx context.Context) bool {
+	select {
+	case <-networkResolved:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// GetChainDataDir returns directory for data of the active chain within the data dir,
+// networks share protocols but not their constants and rights. There is none until the chain id is known.
+func GetChainDataDir(dataDir string) (string, bool) {
+	network := GetNetworkInfo()
+	if network == nil || network.ChainId == "" {
+		return "", false
+	}
+	return filepath.Join(dataDir, network.ChainId), true
+}
+
+func getChainId(ctx context.Context, client *rpc.Client) (string, error) {
+	var chainId string
+	err := client.Get(ctx, "chains/main/chain_id", &chainId)
+	return chainId, err
+}
+
+func getNodeChainId(ctx context.Context, address string) (string, error) {
+	client, err := rpc.NewClient(address, defaultHttpClient)
+	if err != nil {
+		return "", err
+	}
+	return getChainId(ctx, client)
+}
+
+// getReferenceNodes returns essential nodes (the baker node) first, then by priority.
+// Default nodes include public nodes of the default network, only essential nodes are used then.
+func getReferenceNodes(nodes map[string]configuration.TezosNode, defaultNodes bool) []string {
+	ids := lo.Keys(nodes)
+	if defaultNodes {
+		ids = lo.Filter(ids, func(id string, _ int) bool { return nodes[id].IsEssential })
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		if nodes[a].IsEssential != nodes[b].IsEssential {
+			if nodes[a].IsEssential {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(nodes[b].Priority, nodes[a].Priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return ids
+}
+
+// detectChainId asks reference nodes for the chain id, the first one answering wins
+func detectChainId(ctx context.Context, nodes map[string]configuration.TezosNode, referenceNodes []string) (string, error) {
+	for _, id := range referenceNodes {
+		chainId, err := getNodeChainId(ctx, nodes[id].Address)
+		if err != nil {
+			slog.Debug("failed to get node chain id", "id", id, "error", err.Error())
+			continue
+		}
+		return chainId, nil
+	}
+	return "", fmt.Errorf("%w: none of %v answered", constants.ErrNetworkNotDetected, referenceNodes)
+}
+
+// setResolvedNetwork makes the network with the chain id active, unnamed networks are named by the chain id.
+// If nodes were not configured, default nodes of the network are used.
+func setResolvedNetwork(config *configuration.Runtime, chainId string) *NetworkInfo {
+	network := config.Network
+	if network == "" {
+		if detected, ok := configuration.GetNetworkByChainId(chainId); ok {
+			network = detected
+		} else {
+			network = chainId
+		}
+		slog.Info("network detected", "network", network, "chain_id", chainId)
+		config.SetNetwork(network)
+	}
+
+	networkMtx.Lock()
+	defer networkMtx.Unlock()
+	activeNetwork = &NetworkInfo{
+		Network: network,
+		ChainId: chainId,
+	}
+	close(networkResolved)
+	return activeNetwork
+}
+
+// ResolveNetwork determines network and chain id tezpeak operates on.
+// Configured network takes precedence, otherwise it is detected from the chain id of the baker node
+// or configured nodes, never from public default nodes which follow the default network.
+// If no node answers, the returned network has no chain id and is detected in background by StartNodeStatusProviders.
+func ResolveNetwork(ctx context.Context, config *configuration.Runtime) *NetworkInfo {
+	if chainId, known := configuration.GetNetworkChainId(config.Network); known {
+		return setResolvedNetwork(config, chainId)
+	}
+	chainId, err := detectChainId(ctx, config.Nodes, getReferenceNodes(config.Nodes, config.UsesDefaultNodes()))
+	if err != nil {
+		slog.Warn("network not detected yet, nodes will be used once it is", "error", err.Error())
+		networkMtx.Lock()
+		defer networkMtx.Unlock()
+		activeNetwork = &NetworkInfo{Network: config.Network}
+		return activeNetwork
+	}
+	return setResolvedNetwork(config, chainId)
+}
+
+// detectNetwork retries network detection until a reference node answers.
+// Default public nodes follow the detected network, so they are started only then.
+func detectNetwork(ctx context.Context, config *configuration.Runtime, statusChannel chan<- StatusUpdate) {
+	usesDefaultNodes := config.UsesDefaultNodes()
+	referenceNodes := getReferenceNodes(config.Nodes, usesDefaultNodes)
+	ticker := time.NewTicker(constants.NETWORK_DETECTION_RETRY_INTERVAL * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		chainId, err := detectChainId(ctx, config.Nodes, referenceNodes)
+		if err != nil {
+			slog.Debug("network not detected yet", "error", err.Error())
+			continue
+		}
+		network := setResolvedNetwork(config, chainId)
+		nodes := map[string]configuration.TezosNode{}
+		if usesDefaultNodes {
+			nodes = lo.OmitBy(config.Nodes, func(_ string, node configuration.TezosNode) bool { return node.IsEssential })
+		}
+		manager.startDeferred(nodes)
+		statusChannel <- &NetworkStatusUpdate{Network: *network}
+		return
+	}
+}
+
+type nodeChainVerification struct {
+	id      string
+	chainId string
+	err     error
+}
+
+// verifyNodeChains checks chain id of all nodes in parallel
+func verifyNodeChains(ctx context.Context, nodes map[string]configuration.TezosNode) []nodeChainVerification {
+	results := make([]nodeChainVerification, 0, len(nodes))
+	mtx := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for id, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chainId, err := getNodeChainId(ctx, node.Address)
+			mtx.Lock()
+			defer mtx.Unlock()
+			results = append(results, nodeChainVerification{id: id, chainId: chainId, err: err})
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(results, func(a, b nodeChainVerification) int { return cmp.Compare(a.id, b.id) })
+	return results
+}
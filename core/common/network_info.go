@@ -0,0 +1,245 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+type NodeNetworkStats struct {
+	TotalSent      int64 `json:"total_sent,string"`
+	TotalRecv      int64 `json:"total_recv,string"`
+	CurrentInflow  int64 `json:"current_inflow"`
+	CurrentOutflow int64 `json:"current_outflow"`
+}
+
+// NodeBandwidth is average traffic in bytes per second between two polls
+type NodeBandwidth struct {
+	Inbound  int64 `json:"inbound"`
+	Outbound int64 `json:"outbound"`
+}
+
+type NodePeerTraffic struct {
+	PeerId    string `json:"peer_id"`
+	Address   string `json:"address,omitempty"`
+	Incoming  bool   `json:"incoming"`
+	TotalSent int64  `json:"total_sent"`
+	TotalRecv int64  `json:"total_recv"`
+}
+
+type NodePeersInfo struct {
+	Incoming         int               `json:"incoming"`
+	Outgoing         int               `json:"outgoing"`
+	Private          int               `json:"private"`
+	Public           int               `json:"public"`
+	TopPeers         []NodePeerTraffic `json:"top_peers"`
+	GreylistedPoints int               `json:"greylisted_points"`
+	GreylistedIps    int               `json:"greylisted_ips"`
+}
+
+type NodeNetworkInfo struct {
+	ConnectionCount int               `json:"connection_count"`
+	Stats           *NodeNetworkStats `json:"stats"`
+	Bandwidth       *NodeBandwidth    `json:"bandwidth,omitempty"`
+	Peers           *NodePeersInfo    `json:"peers,omitempty"`
+	MinPeers        int               `json:"min_peers"`
+	LowPeerCount    bool              `json:"low_peer_count"`
+	UpdatedAt       *time.Time        `json:"updated_at,omitempty"`
+}
+
+type networkConnection struct {
+	Incoming bool   `json:"incoming"`
+	PeerId   string `json:"peer_id"`
+	Private  bool   `json:"private"`
+	IdPoint  struct {
+		Addr string `json:"addr"`
+		Port int    `json:"port"`
+	} `json:"id_point"`
+}
+
+type networkPeerInfo struct {
+	State string           `json:"state"`
+	Stat  NodeNetworkStats `json:"stat"`
+}
+
+type networkPointInfo struct {
+	GreylistedUntil *time.Time `json:"greylisted_until,omitempty"`
+}
+
+type networkGreylistIps struct {
+	Ips []string `json:"ips"`
+}
+
+// getNetworkList loads lists of [id, info] pairs, e.g. network/peers
+func getNetworkList[T any](ctx context.Context, client *rpc.Client, path string) (map[string]T, error) {
+	var pairs [][2]json.RawMessage
+	if err := client.Get(ctx, path, &pairs); err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(pairs))
+	for _, pair := range pairs {
+		var id string
+		var info T
+		if json.Unmarshal(pair[0], &id) != nil || json.Unmarshal(pair[1], &info) != nil {
+			continue
+		}
+		result[id] = info
+	}
+	return result, nil
+}
+
+func getPeersInfo(ctx context.Context, client *rpc.Client, connections []networkConnection) *NodePeersInfo {
+	info := &NodePeersInfo{
+		TopPeers: []NodePeerTraffic{},
+	}
+	addresses := map[string]string{}
+	incoming := map[string]bool{}
+	for _, connection := range connections {
+		if connection.Incoming {
+			info.Incoming++
+		} else {
+			info.Outgoing++
+		}
+		if connection.Private {
+			info.Private++
+		} else {
+			info.Public++
+		}
+		if connection.IdPoint.Addr != "" {
+			addresses[connection.PeerId] = formatPoint(connection.IdPoint.Addr, connection.IdPoint.Port)
+		}
+		incoming[connection.PeerId] = connection.Incoming
+	}
+
+	peers, err := getNetworkList[networkPeerInfo](ctx, client, "network/peers")
+	if err == nil {
+		for peerId, peer := range peers {
+			if peer.State != "running" {
+				continue
+			}
+			info.TopPeers = append(info.TopPeers, NodePeerTraffic{
+				PeerId:    peerId,
+				Address:   addresses[peerId],
+				Incoming:  incoming[peerId],
+				TotalSent: peer.Stat.TotalSent,
+				TotalRecv: peer.Stat.TotalRecv,
+			})
+		}
+		slices.SortFunc(info.TopPeers, func(a, b NodePeerTraffic) int {
+			return cmp.Compare(b.TotalSent+b.TotalRecv, a.TotalSent+a.TotalRecv)
+		})
+		info.TopPeers = info.TopPeers[:min(len(info.TopPeers), constants.NODE_NETWORK_INFO_TOP_PEERS)]
+	} else {
+		slog.Debug("failed to get network peers", "source", client.BaseURL.String(), "error", err.Error())
+	}
+
+	points, err := getNetworkList[networkPointInfo](ctx, client, "network/points")
+	if err == nil {
+		now := time.Now()
+		for _, point := range points {
+			if point.GreylistedUntil != nil && point.GreylistedUntil.After(now) {
+				info.GreylistedPoints++
+			}
+		}
+	} else {
+		slog.Debug("failed to get network points", "source", client.BaseURL.String(), "error", err.Error())
+	}
+
+	var greylist networkGreylistIps
+	if err := client.Get(ctx, "network/greylist/ips", &greylist); err == nil {
+		info.GreylistedIps = len(greylist.Ips)
+	} else {
+		slog.Debug("failed to get greylisted ips", "source", client.BaseURL.String(), "error", err.Error())
+	}
+	return info
+}
+
+func formatPoint(addr string, port int) string {
+	if port == 0 {
+		return addr
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+// pollNetworkInfo refreshes network info of the node, bandwidth is computed from previous stats
+func pollNetworkInfo(ctx context.Context, client *rpc.Client, previous *NodeNetworkInfo, minPeers int) *NodeNetworkInfo {
+	info := &NodeNetworkInfo{
+		MinPeers: minPeers,
+	}
+	if previous != nil {
+		// keep last known values if node does not answer
+		info.ConnectionCount = previous.ConnectionCount
+		info.Stats = previous.Stats
+		info.Peers = previous.Peers
+		info.LowPeerCount = previous.LowPeerCount
+	}
+
+	var connections []networkConnection
+	err := client.Get(ctx, "network/connections", &connections)
+	if err == nil {
+		info.ConnectionCount = len(connections)
+		info.Peers = getPeersInfo(ctx, client, connections)
+		info.LowPeerCount = info.ConnectionCount < minPeers
+	} else {
+		slog.Debug("failed to get network connections", "source", client.BaseURL.String(), "error", err.Error())
+	}
+
+	var stats NodeNetworkStats
+	if err := client.Get(ctx, "network/stat", &stats); err == nil {
+		now := time.Now()
+		if previous != nil && previous.Stats != nil && previous.UpdatedAt != nil {
+			if elapsed := now.Sub(*previous.UpdatedAt).Seconds(); elapsed > 0 {
+				info.Bandwidth = &NodeBandwidth{
+					Inbound:  max(int64(float64(stats.TotalRecv-previous.Stats.TotalRecv)/elapsed), 0),
+					Outbound: max(int64(float64(stats.TotalSent-previous.Stats.TotalSent)/elapsed), 0),
+				}
+			}
+		}
+		info.Stats = &stats
+		info.UpdatedAt = &now
+	} else {
+		slog.Debug("failed to get network stats", "source", client.BaseURL.String(), "error", err.Error())
+		if previous != nil {
+			info.UpdatedAt = previous.UpdatedAt
+		}
+	}
+	return info
+}
+
+func (n *poolNode) runNetworkInfoPolling(ctx context.Context) {
+	minPeers := n.MinPeers
+	if minPeers <= 0 {
+		minPeers = constants.DEFAULT_NODE_MIN_PEERS
+	}
+
+	ticker := time.NewTicker(constants.NODE_NETWORK_INFO_INTERVAL * time.Second)
+	defer ticker.Stop()
+	var info *NodeNetworkInfo
+	for {
+		previous := info
+		info = pollNetworkInfo(ctx, n.Client, previous, minPeers)
+		if info.LowPeerCount && (previous == nil || !previous.LowPeerCount) {
+			slog.Warn("node peer count dropped below threshold", "id", n.id, "source", n.Address, "peers", info.ConnectionCount, "min_peers", minPeers)
+		}
+		if !info.LowPeerCount && previous != nil && previous.LowPeerCount {
+			slog.Info("node peer count recovered", "id", n.id, "source", n.Address, "peers", info.ConnectionCount)
+		}
+		n.reporter.update(func(s *NodeStatus) {
+			s.NetworkInfo = info
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
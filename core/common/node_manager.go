@@ -0,0 +1,229 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+type ManagedNode struct {
+	configuration.TezosNode
+	// false while the node waits for chain verification
+	Active bool `json:"active"`
+}
+
+type managedNode struct {
+	configuration.TezosNode
+	cancel context.CancelFunc
+}
+
+// nodeManager owns lifecycle of nodes, each node runs in its own context so it can be stopped at runtime
+type nodeManager struct {
+	mtx           sync.Mutex
+	ctx           context.Context
+	statusChannel chan<- StatusUpdate
+	nodes         map[string]*managedNode
+}
+
+var (
+	manager = &nodeManager{
+		ctx:   context.Background(),
+		nodes: map[string]*managedNode{},
+	}
+)
+
+func (m *nodeManager) init(ctx context.Context, statusChannel chan<- StatusUpdate) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.ctx = ctx
+	m.statusChannel = statusChannel
+}
+
+// start runs the node, unverified nodes are activated once their chain is verified
+func (m *nodeManager) start(id string, node configuration.TezosNode, verified bool) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.nodes[id] = &managedNode{TezosNode: node, cancel: cancel}
+	if verified {
+		activateNode(ctx, id, node, m.statusChannel)
+	} else {
+		go activateNodeOnceVerified(ctx, id, node, m.statusChannel)
+	}
+}
+
+// reject keeps the node managed without using it, so it can be updated or removed
+func (m *nodeManager) reject(id string, node configuration.TezosNode, err error) {
+	_, cancel := context.WithCancel(m.ctx)
+	m.nodes[id] = &managedNode{TezosNode: node, cancel: cancel}
+	reportRejectedNode(id, node, err, m.statusChannel)
+}
+
+// startDeferred starts nodes which could not be started before the network was detected
+func (m *nodeManager) startDeferred(nodes map[string]configuration.TezosNode) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for id, node := range nodes {
+		if _, ok := m.nodes[id]; ok {
+			continue // added meanwhile
+		}
+		m.start(id, node, false)
+	}
+}
+
+// stop cancels node context which tears down its block monitor and checks
+func (m *nodeManager) stop(id string) {
+	node, ok := m.nodes[id]
+	if !ok {
+		return
+	}
+	node.cancel()
+	delete(m.nodes, id)
+	if active, ok := pool.remove(id); ok {
+		active.reporter.close()
+	} else {
+		// waiting or rejected node may have reported its status
+		m.statusChannel <- &NodeRemovedUpdate{Id: id}
+	}
+	consensus.forget(id)
+}
+
+// verifyChain checks the node is on the expected chain, it is called without the manager lock as it waits for the node
+func verifyChain(ctx context.Context, node configuration.TezosNode, expectedChainId string) error {
+	chainId, err := getNodeChainId(ctx, node.Address)
+	if err != nil {
+		return fmt.Errorf("failed to verify node chain: %w", err)
+	}
+	if chainId != expectedChainId {
+		return fmt.Errorf("%w: node %s is on chain %s, expected %s", constants.ErrNodeChainMismatch, node.Address, chainId, expectedChainId)
+	}
+	return nil
+}
+
+// checkProviders makes sure there is block and rights provider left after the change
+func (m *nodeManager) checkProviders(id string, node *configuration.TezosNode) error {
+	nodes := lo.MapValues(m.nodes, func(n *managedNode, _ string) configuration.TezosNode { return n.TezosNode })
+	if node == nil {
+		delete(nodes, id)
+	} else {
+		nodes[id] = *node
+	}
+	if !lo.SomeBy(lo.Values(nodes), func(n configuration.TezosNode) bool { return n.IsBlockProvider }) {
+		return fmt.Errorf("%w: no block provider left", constants.ErrInvalidNodes)
+	}
+	if !lo.SomeBy(lo.Values(nodes), func(n configuration.TezosNode) bool { return n.IsRightsProvider }) {
+		return fmt.Errorf("%w: no rights provider left", constants.ErrInvalidNodes)
+	}
+	return nil
+}
+
+func ListNodes() map[string]ManagedNode {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	result := make(map[string]ManagedNode, len(manager.nodes))
+	for id, node := range manager.nodes {
+		_, active := pool.get(id)
+		result[id] = ManagedNode{TezosNode: node.TezosNode, Active: active}
+	}
+	return result
+}
+
+// GetNodes returns configuration of currently managed nodes
+func GetNodes() map[string]configuration.TezosNode {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	return lo.MapValues(manager.nodes, func(node *managedNode, _ string) configuration.TezosNode { return node.TezosNode })
+}
+
+// AddNode verifies the node is reachable and on the network chain and starts it
+func AddNode(ctx context.Context, id string, node configuration.TezosNode) error {
+	if err := node.Validate(); err != nil {
+		return err
+	}
+	manager.mtx.Lock()
+	_, exists := manager.nodes[id]
+	manager.mtx.Unlock()
+	if exists {
+		return fmt.Errorf("%w: %s", constants.ErrNodeAlreadyExists, id)
+	}
+	// before the network is detected nodes wait for it
+	chainId := GetNetworkInfo().ChainId
+	verified := chainId != ""
+	if verified {
+		if err := verifyChain(ctx, node, chainId); err != nil {
+			return err
+		}
+	}
+
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	if _, ok := manager.nodes[id]; ok {
+		return fmt.Errorf("%w: %s", constants.ErrNodeAlreadyExists, id) // added meanwhile
+	}
+	manager.start(id, node, verified)
+	slog.Info("node added", "id", id, "source", node.Address)
+	return nil
+}
+
+// UpdateNode restarts the node with the new configuration
+func UpdateNode(ctx context.Context, id string, node configuration.TezosNode) error {
+	if err := node.Validate(); err != nil {
+		return err
+	}
+	manager.mtx.Lock()
+	current, ok := manager.nodes[id]
+	var err error
+	if ok {
+		err = manager.checkProviders(id, &node)
+	}
+	manager.mtx.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", constants.ErrNodeNotFound, id)
+	}
+	if err != nil {
+		return err
+	}
+	chainId := GetNetworkInfo().ChainId
+	addressVerified := current.Address != node.Address && chainId != ""
+	if addressVerified {
+		if err := verifyChain(ctx, node, chainId); err != nil {
+			return err
+		}
+	}
+
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	if manager.nodes[id] != current {
+		return fmt.Errorf("%w: %s", constants.ErrNodeChanged, id) // updated or removed during verification
+	}
+	if err := manager.checkProviders(id, &node); err != nil {
+		return err // other nodes changed during verification
+	}
+	// node waiting for verification keeps waiting unless its new address was verified
+	_, verified := pool.get(id)
+	if addressVerified {
+		verified = true
+	}
+	manager.stop(id)
+	manager.start(id, node, verified)
+	slog.Info("node updated", "id", id, "source", node.Address)
+	return nil
+}
+
+func RemoveNode(id string) error {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	node, ok := manager.nodes[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", constants.ErrNodeNotFound, id)
+	}
+	if err := manager.checkProviders(id, nil); err != nil {
+		return err
+	}
+	manager.stop(id)
+	slog.Info("node removed", "id", id, "source", node.Address)
+	return nil
+}
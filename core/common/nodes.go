@@ -1,173 +1,294 @@
-package common
-
-import (
-	"context"
-	"log/slog"
-	"net/http"
-	"sort"
-	"strings"
-	"time"
-
-	"github.com/samber/lo"
-	"github.com/tez-capital/tezpeak/configuration"
-	"github.com/tez-capital/tezpeak/constants"
-	"github.com/trilitech/tzgo/rpc"
-)
-
-type NodeNetworkInfo struct {
-	ConnectionCount int               `json:"connection_count"`
-	Stats           *rpc.NetworkStats `json:"stats"`
-}
-
-type NodeStatus struct {
-	Url              string           `json:"address"`
-	ConnectionStatus ConnectionStatus `json:"connection_status"`
-	Block            *Block           `json:"block"`
-	NetworkInfo      *NodeNetworkInfo `json:"network_info"`
-	IsEssential      bool             `json:"is_essential"`
-}
-
-type NodeStatusUpdate struct {
-	Id     string
-	Status NodeStatus
-}
-
-func (s *NodeStatusUpdate) GetId() string {
-	return s.Id
-}
-
-func (s *NodeStatusUpdate) GetData() any {
-	return s.Status
-}
-
-type ActiveRpcNode struct {
-	configuration.TezosNode
-	*rpc.Client
-}
-
-var (
-	activeRpcNodes    = make(map[string]*ActiveRpcNode)
-	defaultHttpClient = &http.Client{
-		Timeout: constants.DEFAULT_HTTP_TIMEOUT_SECONDS * time.Second,
-	}
-)
-
-func updateNetworkInfo(ctx context.Context, client *rpc.Client, nodeStatus *NodeStatus) {
-	connections, err := client.GetNetworkConnections(ctx)
-	if err == nil {
-		nodeStatus.NetworkInfo.ConnectionCount = len(connections)
-	} else {
-		slog.Debug("failed to get network connections", "source", client.BaseURL.String(), "error", err.Error())
-	}
-
-	stats, err := client.GetNetworkStats(ctx)
-	if err == nil {
-		nodeStatus.NetworkInfo.Stats = stats
-	} else {
-		slog.Debug("failed to get network stats", "source", client.BaseURL.String(), "error", err.Error())
-	}
-
-}
-
-func StartNodeStatusProviders(ctx context.Context, nodes map[string]configuration.TezosNode, statusChannel chan<- StatusUpdate) {
-	for nodeId, node := range nodes {
-		if _, ok := activeRpcNodes[nodeId]; ok {
-			slog.Warn("node already active", "source", node.Address, "id", nodeId)
-			continue
-		}
-		client, err := rpc.NewClient(node.Address, defaultHttpClient)
-		if err != nil {
-			slog.Warn("failed to connect to node", "source", node.Address, "error", err.Error())
-			continue
-		}
-
-		activeRpcNodes[nodeId] = &ActiveRpcNode{
-			TezosNode: node,
-			Client:    client,
-		}
-
-		if !node.IsBlockProvider {
-			continue
-		}
-		// default blockMonitorClient uses timeout, but for streaming we do not want one so use nil instead
-		blockMonitorClient, err := rpc.NewClient(node.Address, nil)
-		if err != nil {
-			slog.Warn("failed to connect to node", "source", node.Address, "error", err.Error())
-			continue
-		}
-
-		nodeStatus := NodeStatus{
-			Url:              node.Address,
-			ConnectionStatus: Disconnected,
-			Block:            nil,
-			NetworkInfo:      nil,
-			IsEssential:      node.IsEssential,
-		}
-
-		if node.IsNetworkInfoProvider {
-			nodeStatus.NetworkInfo = &NodeNetworkInfo{}
-		}
-
-		monitorId, err := AddBlockMonitor(ctx, blockMonitorClient, func(status ConnectionStatus) {
-			nodeStatus.ConnectionStatus = status
-
-			if node.IsNetworkInfoProvider {
-				updateNetworkInfo(ctx, blockMonitorClient, &nodeStatus)
-			}
-
-			statusChannel <- &NodeStatusUpdate{
-				Id:     nodeId,
-				Status: nodeStatus,
-			}
-		}, func(h *Block) {
-			nodeStatus.Block = h
-
-			if node.IsNetworkInfoProvider {
-				updateNetworkInfo(ctx, blockMonitorClient, &nodeStatus)
-			}
-
-			statusChannel <- &NodeStatusUpdate{
-				Id:     nodeId,
-				Status: nodeStatus,
-			}
-		})
-		if err != nil {
-			slog.Warn("failed to add block monitor", "source", blockMonitorClient.BaseURL.String(), "error", err.Error())
-			continue
-		}
-
-		go func() {
-			<-ctx.Done()
-			RemoveBlockMonitor(monitorId)
-		}()
-	}
-}
-
-func isClientSynced(ctx context.Context, client *rpc.Client) bool {
-	status, err := client.GetStatus(ctx)
-	return status.SyncState == "synced" || (err != nil && strings.Contains(err.Error(), "status 403"))
-}
-
-func AttemptWithRpcClients[T any](ctx context.Context, f func(client *ActiveRpcNode) (T, error)) (T, error) {
-	var err error
-	var result T
-
-	nodesByPriority := lo.Values(activeRpcNodes)
-	sort.Slice(nodesByPriority, func(i, j int) bool {
-		return nodesByPriority[i].Priority < nodesByPriority[j].Priority
-	})
-
-	for _, node := range activeRpcNodes {
-		if !isClientSynced(ctx, node.Client) {
-			continue
-		}
-		slog.Debug("attempting with client", "client", node.Client.BaseURL.Host)
-
-		result, err = f(node)
-		if err != nil {
-			continue
-		}
-		return result, nil
-	}
-	return result, err
-}
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+type NodeStatus struct {
+	Url              string           `json:"address"`
+	ConnectionStatus ConnectionStatus `json:"connection_status"`
+	Block            *Block           `json:"block"`
+	NetworkInfo      *NodeNetworkInfo `json:"network_info"`
+	IsEssential      bool             `json:"is_essential"`
+	Pool             *NodePoolStatus  `json:"pool,omitempty"`
+	// position relative to the network head, reported by block providers
+	Consensus *NodeConsensusStatus `json:"consensus,omitempty"`
+	*NodeVersionInfo
+	// sync state transitions, unsynced and stuck nodes are not used
+	Sync *NodeSyncStatus `json:"sync,omitempty"`
+	// endpoint families the node allows, missing ones were not probed yet
+	Capabilities map[RpcCapability]bool `json:"capabilities,omitempty"`
+	// configured roles the node can actually serve
+	Roles []NodeRole `json:"roles"`
+	// request statistics by path category
+	Rpc map[RpcCategory]RpcCategoryStats `json:"rpc,omitempty"`
+	// why the node is not used, e.g. it is on a different chain
+	Error string `json:"error,omitempty"`
+}
+
+type NodeStatusUpdate struct {
+	Id     string
+	Status NodeStatus
+}
+
+func (s *NodeStatusUpdate) GetId() string {
+	return s.Id
+}
+
+func (s *NodeStatusUpdate) GetData() any {
+	return s.Status
+}
+
+type ActiveRpcNode struct {
+	configuration.TezosNode
+	*rpc.Client
+	capabilities *nodeCapabilities
+}
+
+var (
+	defaultHttpClient = &http.Client{
+		Timeout: constants.DEFAULT_HTTP_TIMEOUT_SECONDS * time.Second,
+	}
+)
+
+type NodeRemovedUpdate struct {
+	Id string
+}
+
+func (s *NodeRemovedUpdate) GetId() string {
+	return s.Id
+}
+
+func (s *NodeRemovedUpdate) GetData() any {
+	return nil
+}
+
+// nodeStatusReporter serializes updates of node status coming from block monitor and pool health checks
+type nodeStatusReporter struct {
+	id            string
+	mtx           sync.Mutex
+	status        NodeStatus
+	statusChannel chan<- StatusUpdate
+	closed        bool
+}
+
+func (r *nodeStatusReporter) update(f func(status *NodeStatus)) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return
+	}
+	f(&r.status)
+
+	// sent under lock so no update is reported after the node is removed
+	r.statusChannel <- &NodeStatusUpdate{
+		Id:     r.id,
+		Status: r.status,
+	}
+}
+
+func (r *nodeStatusReporter) close() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.statusChannel <- &NodeRemovedUpdate{Id: r.id}
+}
+
+func activateNode(ctx context.Context, nodeId string, node configuration.TezosNode, statusChannel chan<- StatusUpdate) {
+	metrics := newRpcMetrics()
+	client, err := rpc.NewClient(node.Address, newInstrumentedHttpClient(metrics, constants.DEFAULT_HTTP_TIMEOUT_SECONDS*time.Second))
+	if err != nil {
+		slog.Warn("failed to connect to node", "source", node.Address, "error", err.Error())
+		return
+	}
+
+	nodeStatus := NodeStatus{
+		Url:              node.Address,
+		ConnectionStatus: Disconnected,
+		Block:            nil,
+		NetworkInfo:      nil,
+		IsEssential:      node.IsEssential,
+		Roles:            getNodeRoles(node, nil),
+	}
+
+	reporter := &nodeStatusReporter{
+		id:            nodeId,
+		status:        nodeStatus,
+		statusChannel: statusChannel,
+	}
+	activeNode := newPoolNode(nodeId, &ActiveRpcNode{
+		TezosNode:    node,
+		Client:       client,
+		capabilities: newNodeCapabilities(),
+	}, reporter, metrics)
+	if !pool.add(activeNode) {
+		slog.Warn("node already active", "source", node.Address, "id", nodeId)
+		return
+	}
+	if ctx.Err() != nil {
+		// removed while activating
+		pool.remove(nodeId)
+		return
+	}
+	go activeNode.runCapabilityProbes(ctx)
+	go activeNode.runHealthChecks(ctx)
+	go activeNode.runSyncWatcher(ctx)
+	go activeNode.runVersionChecks(ctx)
+	if node.IsNetworkInfoProvider {
+		go activeNode.runNetworkInfoPolling(ctx)
+	}
+
+	if !node.IsBlockProvider {
+		return
+	}
+	// default http client uses timeout, but for streaming we do not want one
+	blockMonitorClient, err := rpc.NewClient(node.Address, newInstrumentedHttpClient(metrics, 0))
+	if err != nil {
+		slog.Warn("failed to connect to node", "source", node.Address, "error", err.Error())
+		return
+	}
+
+	monitorId, err := AddBlockMonitor(ctx, blockMonitorClient, func(status ConnectionStatus) {
+		reporter.update(func(nodeStatus *NodeStatus) {
+			nodeStatus.ConnectionStatus = status
+		})
+	}, func(h *Block) {
+		if ctx.Err() != nil {
+			return // node removed
+		}
+		history.observe(nodeId, h)
+		cycleEventSource.observe(h)
+		var consensusStatus *NodeConsensusStatus
+		if h.LevelInfo != nil {
+			activeNode.setHeadLevel(h.LevelInfo.Level)
+			status := consensus.observe(nodeId, nodeHead{
+				level:       h.LevelInfo.Level,
+				hash:        h.Hash,
+				predecessor: h.Predecessor,
+				timestamp:   h.Timestamp,
+			})
+			consensusStatus = &status
+		}
+		poolStatus := activeNode.getStatus()
+
+		reporter.update(func(nodeStatus *NodeStatus) {
+			nodeStatus.Block = h
+			nodeStatus.Pool = &poolStatus
+			if consensusStatus != nil {
+				nodeStatus.Consensus = consensusStatus
+			}
+		})
+	})
+	if err != nil {
+		slog.Warn("failed to add block monitor", "source", blockMonitorClient.BaseURL.String(), "error", err.Error())
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		RemoveBlockMonitor(monitorId)
+	}()
+}
+
+// reportRejectedNode reports node which is not used, it stays managed so it can be updated or removed
+func reportRejectedNode(nodeId string, node configuration.TezosNode, err error, statusChannel chan<- StatusUpdate) {
+	slog.Error("node is not used", "id", nodeId, "source", node.Address, "error", err.Error())
+	statusChannel <- &NodeStatusUpdate{
+		Id: nodeId,
+		Status: NodeStatus{
+			Url:              node.Address,
+			ConnectionStatus: Disconnected,
+			IsEssential:      node.IsEssential,
+			Roles:            getNodeRoles(node, nil),
+			Error:            err.Error(),
+		},
+	}
+}
+
+// activateNodeOnceVerified waits until the network chain is known and the node answers,
+// then activates the node if it is on the network chain
+func activateNodeOnceVerified(ctx context.Context, nodeId string, node configuration.TezosNode, statusChannel chan<- StatusUpdate) {
+	if !WaitForNetwork(ctx) {
+		return
+	}
+	ticker := time.NewTicker(constants.NODE_CHAIN_VERIFICATION_INTERVAL * time.Second)
+	defer ticker.Stop()
+
+	for {
+		err := verifyChain(ctx, node, GetNetworkInfo().ChainId)
+		if ctx.Err() != nil {
+			return // removed meanwhile
+		}
+		switch {
+		case err == nil:
+			slog.Info("node chain verified", "id", nodeId, "source", node.Address)
+			activateNode(ctx, nodeId, node, statusChannel)
+			return
+		case errors.Is(err, constants.ErrNodeChainMismatch):
+			reportRejectedNode(nodeId, node, err, statusChannel)
+			return
+		}
+		slog.Debug("failed to verify node chain", "id", nodeId, "source", node.Address, "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// StartNodeStatusProviders verifies nodes are on the network chain and starts monitoring them.
+// Nodes on a different chain are reported and not used, unreachable nodes are activated once verified.
+// If the network is not known yet, it is detected in background and nodes wait for it.
+func StartNodeStatusProviders(ctx context.Context, config *configuration.Runtime, network *NetworkInfo, statusChannel chan<- StatusUpdate) {
+	consensus.setStatusChannel(statusChannel)
+	cycleEventSource.setStatusChannel(statusChannel)
+	manager.init(ctx, statusChannel)
+	go runVersionRefreshOnProtocolChange(ctx)
+
+	nodes := config.Nodes
+	if network.ChainId == "" {
+		if config.UsesDefaultNodes() {
+			// public default nodes follow the detected network
+			nodes = lo.PickBy(nodes, func(_ string, node configuration.TezosNode) bool { return node.IsEssential })
+		}
+		manager.mtx.Lock()
+		for nodeId, node := range nodes {
+			manager.start(nodeId, node, false)
+		}
+		manager.mtx.Unlock()
+		go detectNetwork(ctx, config, statusChannel)
+		return
+	}
+
+	results := verifyNodeChains(ctx, nodes)
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	for _, result := range results {
+		node := nodes[result.id]
+		switch {
+		case result.err != nil:
+			slog.Warn("failed to verify node chain, node will be used once verified", "id", result.id, "source", node.Address, "error", result.err.Error())
+			manager.start(result.id, node, false)
+		case result.chainId != network.ChainId:
+			manager.reject(result.id, node, fmt.Errorf("%w: node %s is on chain %s, expected %s (%s)", constants.ErrNodeChainMismatch, node.Address, result.chainId, network.ChainId, network.Network))
+		default:
+			manager.start(result.id, node, true)
+		}
+	}
+}
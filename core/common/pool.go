@@ -0,0 +1,410 @@
+package common
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// NodePoolStatus is the health of a node as tracked by the pool
+type NodePoolStatus struct {
+	Synced    bool   `json:"synced"`
+	SyncState string `json:"sync_state,omitempty"`
+	HeadLevel int64  `json:"head_level"`
+	// moving average of request latency in milliseconds
+	Latency int64 `json:"latency"`
+	// moving average of failed requests, 0 - 1
+	ErrorRate float64      `json:"error_rate"`
+	Score     float64      `json:"score"`
+	Circuit   CircuitState `json:"circuit"`
+	RetryAt   *time.Time   `json:"retry_at,omitempty"`
+	LastError string       `json:"last_error,omitempty"`
+	CheckedAt *time.Time   `json:"checked_at,omitempty"`
+}
+
+type poolNode struct {
+	*ActiveRpcNode
+	id       string
+	reporter *nodeStatusReporter
+	metrics  *rpcMetrics
+
+	mtx                 sync.RWMutex
+	status              NodePoolStatus
+	latency             float64
+	consecutiveFailures int
+	backoff             time.Duration
+	openUntil           time.Time
+	probing             bool
+	headUpdatedAt       time.Time
+	sync                NodeSyncStatus
+
+	versionRefresh chan struct{}
+}
+
+type nodePool struct {
+	mtx   sync.RWMutex
+	nodes map[string]*poolNode
+}
+
+var (
+	pool = &nodePool{
+		nodes: map[string]*poolNode{},
+	}
+)
+
+func newPoolNode(id string, node *ActiveRpcNode, reporter *nodeStatusReporter, metrics *rpcMetrics) *poolNode {
+	return &poolNode{
+		ActiveRpcNode: node,
+		id:            id,
+		reporter:      reporter,
+		metrics:       metrics,
+		status: NodePoolStatus{
+			Synced:  true, // assume synced until checked
+			Circuit: CircuitClosed,
+		},
+		headUpdatedAt:  time.Now(),
+		versionRefresh: make(chan struct{}, 1),
+	}
+}
+
+func (p *nodePool) add(node *poolNode) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if _, ok := p.nodes[node.id]; ok {
+		return false
+	}
+	p.nodes[node.id] = node
+	return true
+}
+
+func (p *nodePool) remove(id string) (*poolNode, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	node, ok := p.nodes[id]
+	delete(p.nodes, id)
+	return node, ok
+}
+
+func (p *nodePool) get(id string) (*poolNode, bool) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	node, ok := p.nodes[id]
+	return node, ok
+}
+
+func (p *nodePool) list() []*poolNode {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	nodes := make([]*poolNode, 0, len(p.nodes))
+	for _, node := range p.nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (p *nodePool) headLevel() int64 {
+	level := int64(0)
+	for _, node := range p.list() {
+		level = max(level, node.getHeadLevel())
+	}
+	return level
+}
+
+// GetHeadLevel returns the highest head level reported by the nodes, 0 if not known yet
+func GetHeadLevel() int64 {
+	return pool.headLevel()
+}
+
+// candidates returns synced nodes with closed circuit ordered by priority and score,
+// unsynced and stuck nodes are returned only if no synced node is available
+func (p *nodePool) candidates() []*poolNode {
+	headLevel := p.headLevel()
+	now := time.Now()
+
+	type candidate struct {
+		node  *poolNode
+		score float64
+	}
+	collect := func(requireSynced bool) []candidate {
+		candidates := []candidate{}
+		for _, node := range p.list() {
+			if !node.isAvailable(now, requireSynced) {
+				continue
+			}
+			candidates = append(candidates, candidate{node: node, score: node.updateScore(headLevel)})
+		}
+		return candidates
+	}
+	candidates := collect(true)
+	if len(candidates) == 0 {
+		candidates = collect(false)
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if c := cmp.Compare(b.node.Priority, a.node.Priority); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.node.id, b.node.id)
+	})
+
+	result := make([]*poolNode, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, candidate.node)
+	}
+	return result
+}
+
+func (n *poolNode) getHeadLevel() int64 {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	return n.status.HeadLevel
+}
+
+func (n *poolNode) setHeadLevel(level int64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if level > n.status.HeadLevel {
+		n.status.HeadLevel = level
+		n.headUpdatedAt = time.Now()
+	}
+}
+
+func (n *poolNode) getStatus() NodePoolStatus {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	return n.status
+}
+
+// isAvailable reports whether node can serve requests, open circuit is half opened for single probe after backoff
+func (n *poolNode) isAvailable(now time.Time, requireSynced bool) bool {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	if requireSynced && !n.status.Synced {
+		return false
+	}
+	switch n.status.Circuit {
+	case CircuitOpen:
+		return !now.Before(n.openUntil)
+	case CircuitHalfOpen:
+		return !n.probing
+	default:
+		return true
+	}
+}
+
+// acquire marks node as probed if its circuit is not closed, returns false if another probe is running
+func (n *poolNode) acquire(now time.Time) bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	switch n.status.Circuit {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if now.Before(n.openUntil) {
+			return false
+		}
+		n.status.Circuit = CircuitHalfOpen
+	}
+	if n.probing {
+		return false
+	}
+	n.probing = true
+	return true
+}
+
+// score prefers nodes with low error rate, low latency and head close to the network head
+func (n *poolNode) updateScore(networkHeadLevel int64) float64 {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	lag := max(networkHeadLevel-n.status.HeadLevel, 0)
+	if n.status.HeadLevel == 0 {
+		lag = 0 // unknown
+	}
+	score := 100*(1-n.status.ErrorRate) - n.latency/100 - float64(lag)*10
+	n.status.Score = float64(int64(score*100)) / 100
+	return score
+}
+
+func (n *poolNode) recordSuccess(latency time.Duration) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.latency == 0 {
+		n.latency = float64(latency.Milliseconds())
+	} else {
+		n.latency += constants.NODE_POOL_EWMA_ALPHA * (float64(latency.Milliseconds()) - n.latency)
+	}
+	n.status.Latency = int64(n.latency)
+	n.status.ErrorRate -= constants.NODE_POOL_EWMA_ALPHA * n.status.ErrorRate
+
+	n.consecutiveFailures = 0
+	n.backoff = 0
+	n.probing = false
+	n.status.Circuit = CircuitClosed
+	n.status.RetryAt = nil
+}
+
+// recordFailure opens the circuit after consecutive failures, failed probe doubles the backoff
+func (n *poolNode) recordFailure(err error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.status.ErrorRate += constants.NODE_POOL_EWMA_ALPHA * (1 - n.status.ErrorRate)
+	n.status.LastError = err.Error()
+	n.consecutiveFailures++
+
+	wasProbing := n.probing
+	n.probing = false
+	if !wasProbing && n.consecutiveFailures < constants.NODE_POOL_CIRCUIT_FAILURE_THRESHOLD {
+		return
+	}
+
+	if n.backoff == 0 {
+		n.backoff = constants.NODE_POOL_CIRCUIT_MIN_BACKOFF * time.Second
+	} else {
+		n.backoff = min(n.backoff*2, constants.NODE_POOL_CIRCUIT_MAX_BACKOFF*time.Second)
+	}
+	if n.status.Circuit != CircuitOpen {
+		slog.Warn("node circuit opened", "id", n.id, "source", n.Address, "backoff", n.backoff.String(), "error", err.Error())
+	}
+	n.openUntil = time.Now().Add(n.backoff)
+	retryAt := n.openUntil
+	n.status.Circuit = CircuitOpen
+	n.status.RetryAt = &retryAt
+}
+
+// release ends probe which did not reach the node (e.g. request not applicable to the node)
+func (n *poolNode) release() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.probing {
+		n.probing = false
+		if n.status.Circuit == CircuitHalfOpen {
+			n.status.Circuit = CircuitOpen
+		}
+	}
+}
+
+// isNodeFailure reports errors caused by the node being unavailable,
+// requests the node answered to (e.g. 404, 403) do not count
+func isNodeFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var rpcError rpc.RPCError
+	if errors.As(err, &rpcError) {
+		return rpcError.StatusCode() >= 500
+	}
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+func (n *poolNode) record(ctx context.Context, started time.Time, err error) {
+	switch {
+	case err == nil:
+		n.recordSuccess(time.Since(started))
+	case isNodeFailure(ctx, err):
+		n.recordFailure(err)
+	default:
+		// node answered
+		n.recordSuccess(time.Since(started))
+	}
+}
+
+type shellHeader struct {
+	Level int64 `json:"level"`
+}
+
+// checkHealth refreshes head level, returns false if the node did not answer. Sync state is tracked by the sync watcher.
+// While the circuit is open the check is skipped until it can be the half-open probe, so it does not extend the backoff.
+func (n *poolNode) checkHealth(ctx context.Context) bool {
+	if !n.acquire(time.Now()) {
+		return false
+	}
+	// timeouts count as failures, so record against the parent context
+	checkCtx, cancel := context.WithTimeout(ctx, constants.NODE_POOL_HEALTH_CHECK_TIMEOUT*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	var header shellHeader
+	err := n.Client.Get(checkCtx, "chains/main/blocks/head/header/shell", &header)
+	n.record(ctx, started, err)
+	if err == nil {
+		n.setHeadLevel(header.Level)
+	}
+
+	now := time.Now()
+	n.mtx.Lock()
+	n.status.CheckedAt = &now
+	n.mtx.Unlock()
+	return !isNodeFailure(ctx, err)
+}
+
+func (n *poolNode) runHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(constants.NODE_POOL_HEALTH_CHECK_INTERVAL * time.Second)
+	defer ticker.Stop()
+	for {
+		ok := n.checkHealth(ctx)
+		n.updateScore(pool.headLevel())
+		status := n.getStatus()
+		rpcStats := n.metrics.snapshot()
+		n.reporter.update(func(s *NodeStatus) {
+			s.Pool = &status
+			s.Rpc = rpcStats
+			if !n.IsBlockProvider {
+				// block providers report connection status from the block monitor
+				s.ConnectionStatus = lo.Ternary(ok, Connected, Disconnected)
+			}
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AttemptWithRpcClients calls f with available nodes ordered by priority and health until it succeeds
+func AttemptWithRpcClients[T any](ctx context.Context, f func(client *ActiveRpcNode) (T, error)) (T, error) {
+	var result T
+	err := constants.ErrNoAvailableNode
+
+	for _, node := range pool.candidates() {
+		if !node.acquire(time.Now()) {
+			continue
+		}
+		slog.Debug("attempting with client", "client", node.Client.BaseURL.Host)
+
+		started := time.Now()
+		result, err = f(node.ActiveRpcNode)
+		if errors.Is(err, constants.ErrNodeNotApplicable) {
+			node.release()
+			continue
+		}
+		node.record(ctx, started, err)
+		if err != nil {
+			continue
+		}
+		return result, nil
+	}
+	return result, err
+}
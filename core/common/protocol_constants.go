@@ -0,0 +1,206 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+// ProtocolConstants is subset of protocol constants tezpeak computes with
+type ProtocolConstants struct {
+	BlocksPerCycle         int64 `json:"blocks_per_cycle"`
+	ConsensusCommitteeSize int64 `json:"consensus_committee_size"`
+	MinimalBlockDelay      int64 `json:"minimal_block_delay,string"`
+	ConsensusRightsDelay   int64 `json:"consensus_rights_delay"`
+}
+
+// protocolConstantsService keeps constants of seen protocols, cached on disk as they never change
+type protocolConstantsService struct {
+	mtx       sync.RWMutex
+	cacheFile string
+	// protocol -> constants as returned by the node
+	raw     map[string]json.RawMessage
+	parsed  map[string]*ProtocolConstants
+	current string
+}
+
+var (
+	protocolConstants = &protocolConstantsService{
+		raw:    map[string]json.RawMessage{},
+		parsed: map[string]*ProtocolConstants{},
+	}
+)
+
+func (s *protocolConstantsService) load(cacheFile string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cacheFile = cacheFile
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read protocol constants cache", "file", cacheFile, "error", err.Error())
+		}
+		return
+	}
+	var cached map[string]json.RawMessage
+	if err := json.Unmarshal(data, &cached); err != nil {
+		slog.Warn("failed to parse protocol constants cache", "file", cacheFile, "error", err.Error())
+		return
+	}
+	for protocol, raw := range cached {
+		var parsed ProtocolConstants
+		if err := json.Unmarshal(raw, &parsed); err != nil {
+			continue
+		}
+		s.raw[protocol] = raw
+		s.parsed[protocol] = &parsed
+	}
+}
+
+// save writes the cache, has to be called under lock
+func (s *protocolConstantsService) save() {
+	if s.cacheFile == "" {
+		return
+	}
+	data, err := json.Marshal(s.raw)
+	if err == nil {
+		err = util.WriteFileAtomic(s.cacheFile, data)
+	}
+	if err != nil {
+		slog.Warn("failed to write protocol constants cache", "file", s.cacheFile, "error", err.Error())
+	}
+}
+
+// get returns constants of the protocol, loading them from the block if not known yet
+func (s *protocolConstantsService) get(ctx context.Context, protocol string, block string) (*ProtocolConstants, error) {
+	s.mtx.RLock()
+	parsed, ok := s.parsed[protocol]
+	s.mtx.RUnlock()
+	if ok {
+		return parsed, nil
+	}
+
+	raw, err := AttemptWithRpcClients(ctx, func(node *ActiveRpcNode) (json.RawMessage, error) {
+		var result json.RawMessage
+		err := node.Get(ctx, fmt.Sprintf("chains/main/blocks/%s/context/constants", block), &result)
+		return result, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	parsed = &ProtocolConstants{}
+	if err := json.Unmarshal(raw, parsed); err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.raw[protocol] = raw
+	s.parsed[protocol] = parsed
+	s.save()
+	slog.Debug("protocol constants loaded", "protocol", protocol)
+	return parsed, nil
+}
+
+func (s *protocolConstantsService) getCurrent() (string, *ProtocolConstants, json.RawMessage, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	parsed, ok := s.parsed[s.current]
+	return s.current, parsed, s.raw[s.current], ok
+}
+
+// refresh loads constants of the head protocol and makes them current
+func (s *protocolConstantsService) refresh(ctx context.Context) error {
+	protocols, err := AttemptWithRpcClients(ctx, func(node *ActiveRpcNode) (blockProtocols, error) {
+		var result blockProtocols
+		err := node.Get(ctx, "chains/main/blocks/head/protocols", &result)
+		return result, err
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.get(ctx, protocols.Protocol, "head"); err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.current = protocols.Protocol
+	return nil
+}
+
+func (s *protocolConstantsService) run(ctx context.Context) {
+	blockChannelId, blockChannel, err := SubscribeToBlockHeaderEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to block events", "error", err.Error())
+		return
+	}
+	defer UnsubscribeFromBlockHeaderEvents(blockChannelId)
+
+	lastProto := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block, ok := <-blockChannel:
+			if !ok {
+				return
+			}
+			if block.Proto == lastProto {
+				continue
+			}
+			if err := s.refresh(ctx); err != nil {
+				slog.Warn("failed to refresh protocol constants", "level", block.Level, "error", err.Error())
+				continue // retried on the next block
+			}
+			lastProto = block.Proto
+		}
+	}
+}
+
+// StartProtocolConstantsService loads cached constants and keeps constants of the head protocol current,
+// both once the network is known as the cache is kept per chain
+func StartProtocolConstantsService(ctx context.Context, dataDir string) {
+	go func() {
+		if !WaitForNetwork(ctx) {
+			return
+		}
+		chainDir, _ := GetChainDataDir(dataDir)
+		protocolConstants.load(filepath.Join(chainDir, constants.PROTOCOL_CONSTANTS_CACHE_FILE))
+		protocolConstants.run(ctx)
+	}()
+}
+
+// GetProtocolConstants returns head protocol and its constants, raw constants are as returned by the node
+func GetProtocolConstants() (string, *ProtocolConstants, json.RawMessage, bool) {
+	return protocolConstants.getCurrent()
+}
+
+// GetProtocolConstantsOf returns raw constants of the protocol if they were loaded
+func GetProtocolConstantsOf(protocol string) (json.RawMessage, bool) {
+	protocolConstants.mtx.RLock()
+	defer protocolConstants.mtx.RUnlock()
+	raw, ok := protocolConstants.raw[protocol]
+	return raw, ok
+}
+
+// estimateTime estimates time after the number of blocks assuming blocks at round 0
+func estimateTime(from time.Time, blocks int64, protocolConstants *ProtocolConstants) time.Time {
+	return from.Add(time.Duration(blocks*protocolConstants.MinimalBlockDelay) * time.Second)
+}
+
+// EstimateLevelTime estimates time of the level from a known block assuming following blocks at round 0
+func EstimateLevelTime(knownLevel int64, knownTimestamp time.Time, level int64) (time.Time, bool) {
+	_, current, _, ok := GetProtocolConstants()
+	if !ok || current.MinimalBlockDelay <= 0 {
+		return time.Time{}, false
+	}
+	return estimateTime(knownTimestamp, level-knownLevel, current), true
+}
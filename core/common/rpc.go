@@ -1 +1,42 @@
-package common
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamRpc reads json values streamed by the node (e.g. monitor RPCs) until the node closes the stream
+func streamRpc[T any](ctx context.Context, node *poolNode, path string, f func(T)) error {
+	address := strings.TrimSuffix(node.Address, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	// streams must not time out
+	resp, err := newInstrumentedHttpClient(node.metrics, 0).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var value T
+		if err := decoder.Decode(&value); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		f(value)
+	}
+}
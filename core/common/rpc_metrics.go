@@ -0,0 +1,212 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RpcCategory string
+
+const (
+	RightsRpcCategory  RpcCategory = "rights"
+	ContextRpcCategory RpcCategory = "context"
+	BlocksRpcCategory  RpcCategory = "blocks"
+	MonitorRpcCategory RpcCategory = "monitor"
+	OtherRpcCategory   RpcCategory = "other"
+)
+
+// upper bounds of latency histogram buckets in milliseconds
+var rpcLatencyBuckets = []int64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+func getRpcCategory(path string) RpcCategory {
+	switch {
+	case strings.Contains(path, "/helpers/baking_rights"), strings.Contains(path, "/helpers/attestation_rights"):
+		return RightsRpcCategory
+	case strings.Contains(path, "/monitor"): // includes mempool/monitor_operations
+		return MonitorRpcCategory
+	case strings.Contains(path, "/context/"):
+		return ContextRpcCategory
+	case strings.Contains(path, "/blocks/"):
+		return BlocksRpcCategory
+	default:
+		return OtherRpcCategory
+	}
+}
+
+// RpcCategoryStats is summary of requests of the category, latency is time until response headers
+type RpcCategoryStats struct {
+	Requests uint64 `json:"requests"`
+	// transport errors and 5xx responses
+	Errors uint64 `json:"errors"`
+	// 4xx responses, e.g. restricted RPC
+	Rejected uint64 `json:"rejected"`
+	// milliseconds
+	AvgLatency int64 `json:"avg_latency"`
+	// upper bound of the bucket the 95th percentile falls into, milliseconds
+	P95Latency int64 `json:"p95_latency"`
+}
+
+type rpcCategoryMetrics struct {
+	requests     uint64
+	errors       uint64
+	rejected     uint64
+	latencySumMs int64
+	// counts per bucket, last one is for latencies above all bounds
+	buckets []uint64
+}
+
+func (m *rpcCategoryMetrics) percentile(p float64) int64 {
+	if m.requests == 0 {
+		return 0
+	}
+	threshold := uint64(math.Ceil(float64(m.requests) * p))
+	cumulative := uint64(0)
+	for i, count := range m.buckets {
+		cumulative += count
+		if cumulative >= threshold && i < len(rpcLatencyBuckets) {
+			return rpcLatencyBuckets[i]
+		}
+	}
+	return rpcLatencyBuckets[len(rpcLatencyBuckets)-1]
+}
+
+type rpcMetrics struct {
+	mtx        sync.Mutex
+	categories map[RpcCategory]*rpcCategoryMetrics
+}
+
+func newRpcMetrics() *rpcMetrics {
+	return &rpcMetrics{
+		categories: map[RpcCategory]*rpcCategoryMetrics{},
+	}
+}
+
+func (m *rpcMetrics) record(category RpcCategory, latency time.Duration, statusCode int, err error) {
+	if errors.Is(err, context.Canceled) {
+		return // canceled by us, e.g. stopped monitor
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	metrics, ok := m.categories[category]
+	if !ok {
+		metrics = &rpcCategoryMetrics{buckets: make([]uint64, len(rpcLatencyBuckets)+1)}
+		m.categories[category] = metrics
+	}
+
+	latencyMs := latency.Milliseconds()
+	metrics.requests++
+	metrics.latencySumMs += latencyMs
+	bucket, _ := slices.BinarySearch(rpcLatencyBuckets, latencyMs)
+	metrics.buckets[bucket]++
+	switch {
+	case err != nil || statusCode >= 500:
+		metrics.errors++
+	case statusCode >= 400:
+		metrics.rejected++
+	}
+}
+
+func (m *rpcMetrics) snapshot() map[RpcCategory]RpcCategoryStats {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	result := make(map[RpcCategory]RpcCategoryStats, len(m.categories))
+	for category, metrics := range m.categories {
+		result[category] = RpcCategoryStats{
+			Requests:   metrics.requests,
+			Errors:     metrics.errors,
+			Rejected:   metrics.rejected,
+			AvgLatency: metrics.latencySumMs / int64(max(metrics.requests, 1)),
+			P95Latency: metrics.percentile(0.95),
+		}
+	}
+	return result
+}
+
+// writePrometheusHistogram writes latency histogram of the node in prometheus text format
+func (m *rpcMetrics) writePrometheusHistogram(w io.Writer, nodeId string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, category := range slices.Sorted(maps.Keys(m.categories)) {
+		metrics := m.categories[category]
+		labels := prometheusLabels(nodeId, category)
+		cumulative := uint64(0)
+		for i, bound := range rpcLatencyBuckets {
+			cumulative += metrics.buckets[i]
+			fmt.Fprintf(w, "tezpeak_rpc_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, float64(bound)/1000, cumulative)
+		}
+		fmt.Fprintf(w, "tezpeak_rpc_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, metrics.requests)
+		fmt.Fprintf(w, "tezpeak_rpc_request_duration_seconds_sum{%s} %g\n", labels, float64(metrics.latencySumMs)/1000)
+		fmt.Fprintf(w, "tezpeak_rpc_request_duration_seconds_count{%s} %d\n", labels, metrics.requests)
+	}
+}
+
+func (m *rpcMetrics) writePrometheusErrors(w io.Writer, nodeId string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, category := range slices.Sorted(maps.Keys(m.categories)) {
+		metrics := m.categories[category]
+		labels := prometheusLabels(nodeId, category)
+		fmt.Fprintf(w, "tezpeak_rpc_errors_total{%s,kind=\"error\"} %d\n", labels, metrics.errors)
+		fmt.Fprintf(w, "tezpeak_rpc_errors_total{%s,kind=\"rejected\"} %d\n", labels, metrics.rejected)
+	}
+}
+
+func prometheusLabels(nodeId string, category RpcCategory) string {
+	nodeId = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(nodeId)
+	return fmt.Sprintf(`node="%s",category="%s"`, nodeId, category)
+}
+
+// instrumentedTransport records latency and result of every request made through it
+type instrumentedTransport struct {
+	base    http.RoundTripper
+	metrics *rpcMetrics
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	t.metrics.record(getRpcCategory(req.URL.Path), time.Since(started), statusCode, err)
+	return resp, err
+}
+
+// newInstrumentedHttpClient creates http client recording into metrics, zero timeout is used for streaming
+func newInstrumentedHttpClient(metrics *rpcMetrics, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &instrumentedTransport{
+			base:    http.DefaultTransport,
+			metrics: metrics,
+		},
+	}
+}
+
+// WriteRpcMetrics writes rpc metrics of all active nodes in prometheus text format
+func WriteRpcMetrics(w io.Writer) {
+	nodes := pool.list()
+	slices.SortFunc(nodes, func(a, b *poolNode) int { return strings.Compare(a.id, b.id) })
+
+	fmt.Fprintln(w, "# HELP tezpeak_rpc_request_duration_seconds Latency of node RPC requests until response headers.")
+	fmt.Fprintln(w, "# TYPE tezpeak_rpc_request_duration_seconds histogram")
+	for _, node := range nodes {
+		node.metrics.writePrometheusHistogram(w, node.id)
+	}
+	fmt.Fprintln(w, "# HELP tezpeak_rpc_errors_total Failed node RPC requests, error are transport errors and 5xx, rejected are 4xx.")
+	fmt.Fprintln(w, "# TYPE tezpeak_rpc_errors_total counter")
+	for _, node := range nodes {
+		node.metrics.writePrometheusErrors(w, node.id)
+	}
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+// RpcProxyResponse is node answer forwarded by the rpc proxy
+type RpcProxyResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Node        string
+	Cached      bool
+}
+
+type rpcProxyCacheEntry struct {
+	response  RpcProxyResponse
+	expiresAt time.Time
+}
+
+// rpcProxyCache keeps responses of immutable block data for a short time
+type rpcProxyCache struct {
+	mtx     sync.Mutex
+	entries map[string]rpcProxyCacheEntry
+}
+
+var (
+	rpcProxyResponses = &rpcProxyCache{
+		entries: map[string]rpcProxyCacheEntry{},
+	}
+)
+
+func (c *rpcProxyCache) get(key string, now time.Time) (RpcProxyResponse, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		return RpcProxyResponse{}, false
+	}
+	return entry.response, true
+}
+
+func (c *rpcProxyCache) set(key string, response RpcProxyResponse, now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.entries) >= constants.RPC_PROXY_CACHE_ENTRIES {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	if len(c.entries) >= constants.RPC_PROXY_CACHE_ENTRIES {
+		return // full of live entries, they expire soon
+	}
+	c.entries[key] = rpcProxyCacheEntry{
+		response:  response,
+		expiresAt: now.Add(constants.RPC_PROXY_CACHE_TTL * time.Second),
+	}
+}
+
+// isImmutableRpcPath reports whether the path refers to data of a block which can not change,
+// blocks referenced by hash or by level deep enough below the head
+func isImmutableRpcPath(rpcPath string, headLevel int64) bool {
+	segments := strings.Split(strings.Trim(rpcPath, "/"), "/")
+	if len(segments) < 4 || segments[0] != "chains" || segments[1] != "main" || segments[2] != "blocks" {
+		return false
+	}
+	block := segments[3]
+	if level, err := strconv.ParseInt(block, 10, 64); err == nil {
+		return headLevel > 0 && level <= headLevel-constants.RPC_PROXY_FINAL_DEPTH
+	}
+	return len(block) == 51 && strings.HasPrefix(block, "B")
+}
+
+func fetchRpc(ctx context.Context, node *poolNode, target string, maxSize int64) (RpcProxyResponse, error) {
+	address := strings.TrimSuffix(node.Address, "/") + "/" + target
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return RpcProxyResponse{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := newInstrumentedHttpClient(node.metrics, constants.DEFAULT_HTTP_TIMEOUT_SECONDS*time.Second).Do(req)
+	if err != nil {
+		return RpcProxyResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return RpcProxyResponse{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return RpcProxyResponse{}, err
+	}
+	if int64(len(body)) > maxSize {
+		return RpcProxyResponse{}, fmt.Errorf("%w: over %d bytes", constants.ErrRpcResponseTooLarge, maxSize)
+	}
+	return RpcProxyResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+		Node:        node.id,
+	}, nil
+}
+
+// ProxyRpc forwards GET request to the best available node, node answers 4xx are forwarded as they are
+func ProxyRpc(ctx context.Context, rpcPath string, query string, maxSize int64) (RpcProxyResponse, error) {
+	rpcPath = strings.Trim(rpcPath, "/")
+	target := rpcPath
+	if query != "" {
+		target += "?" + query
+	}
+	if cached, ok := rpcProxyResponses.get(target, time.Now()); ok {
+		cached.Cached = true
+		return cached, nil
+	}
+
+	err := constants.ErrNoAvailableNode
+	for _, node := range pool.candidates() {
+		if !node.acquire(time.Now()) {
+			continue
+		}
+
+		started := time.Now()
+		var response RpcProxyResponse
+		response, err = fetchRpc(ctx, node, target, maxSize)
+		if errors.Is(err, constants.ErrRpcResponseTooLarge) {
+			node.record(ctx, started, nil) // the node answered, other nodes would answer the same
+			return response, err
+		}
+		node.record(ctx, started, err)
+		if err != nil {
+			continue
+		}
+		if response.StatusCode == http.StatusOK && isImmutableRpcPath(rpcPath, pool.headLevel()) {
+			rpcProxyResponses.set(target, response, time.Now())
+		}
+		return response, nil
+	}
+	return RpcProxyResponse{}, err
+}
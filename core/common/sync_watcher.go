@@ -0,0 +1,166 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+)
+
+type SyncState string
+
+const (
+	SyncStateSynced   SyncState = "synced"
+	SyncStateUnsynced SyncState = "unsynced"
+	SyncStateStuck    SyncState = "stuck"
+)
+
+type SyncTransition struct {
+	State SyncState `json:"state"`
+	At    time.Time `json:"at"`
+}
+
+type NodeSyncStatus struct {
+	State SyncState `json:"state"`
+	Since time.Time `json:"since"`
+	// last time the node advanced its head
+	LastHeadAt time.Time `json:"last_head_at"`
+	// most recent first
+	Transitions []SyncTransition `json:"transitions"`
+}
+
+type bootstrappedStatus struct {
+	Bootstrapped bool      `json:"bootstrapped"`
+	SyncState    SyncState `json:"sync_state"`
+}
+
+type bootstrappedHead struct {
+	Block     string    `json:"block"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// evaluateSyncState combines state reported by the node with its head progress,
+// node without new head for a few block times is stuck even if it claims to be synced,
+// unless other nodes did not get further either (e.g. network-wide round escalation)
+func evaluateSyncState(reported SyncState, lastHeadAt time.Time, now time.Time, blockTime time.Duration, headLevel int64, networkHeadLevel int64) SyncState {
+	switch {
+	case reported == SyncStateUnsynced:
+		return SyncStateUnsynced
+	case now.Sub(lastHeadAt) > blockTime*constants.NODE_SYNC_STUCK_BLOCK_TIMES && headLevel < networkHeadLevel:
+		return SyncStateStuck
+	case reported == SyncStateStuck:
+		return SyncStateStuck
+	default:
+		return SyncStateSynced
+	}
+}
+
+// markHead records head progress of the node
+func (n *poolNode) markHead() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.headUpdatedAt = time.Now()
+}
+
+// updateSyncState evaluates sync state of the node and reports whether it changed
+func (n *poolNode) updateSyncState(reported SyncState) (NodeSyncStatus, bool) {
+	blockTime := getBlockTime()
+	networkHeadLevel := pool.headLevel()
+	now := time.Now()
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	state := evaluateSyncState(reported, n.headUpdatedAt, now, blockTime, n.status.HeadLevel, networkHeadLevel)
+	changed := state != n.sync.State
+	if changed {
+		if n.sync.State != "" || state != SyncStateSynced {
+			slog.Info("node sync state changed", "id", n.id, "source", n.Address, "from", n.sync.State, "to", state)
+		}
+		n.sync.State = state
+		n.sync.Since = now
+		n.sync.Transitions = append([]SyncTransition{{State: state, At: now}}, n.sync.Transitions...)
+		n.sync.Transitions = n.sync.Transitions[:min(len(n.sync.Transitions), constants.NODE_SYNC_TRANSITIONS)]
+	}
+	n.sync.LastHeadAt = n.headUpdatedAt
+	n.status.Synced = state == SyncStateSynced
+	n.status.SyncState = string(state)
+
+	status := n.sync
+	status.Transitions = slices.Clone(n.sync.Transitions)
+	return status, changed
+}
+
+func (n *poolNode) reportSyncStatus(status NodeSyncStatus) {
+	poolStatus := n.getStatus()
+	n.reporter.update(func(s *NodeStatus) {
+		s.Sync = &status
+		s.Pool = &poolStatus
+	})
+}
+
+// checkBootstrapped returns sync state reported by the node and whether it is still bootstrapping
+func (n *poolNode) checkBootstrapped(ctx context.Context) (SyncState, bool) {
+	checkCtx, cancel := context.WithTimeout(ctx, constants.NODE_POOL_HEALTH_CHECK_TIMEOUT*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	var status bootstrappedStatus
+	err := n.Client.Get(checkCtx, "chains/main/is_bootstrapped", &status)
+	n.record(ctx, started, err)
+	switch {
+	case err != nil && strings.Contains(err.Error(), "status 403"):
+		return "", false // restricted, rely on head progress
+	case err != nil:
+		return SyncStateUnsynced, false
+	case !status.Bootstrapped:
+		return SyncStateUnsynced, true
+	default:
+		return status.SyncState, false
+	}
+}
+
+// followBootstrap streams heads of bootstrapping node until the node closes the stream once bootstrapped
+func (n *poolNode) followBootstrap(ctx context.Context) {
+	lastBlock := ""
+	err := streamRpc(ctx, n, "monitor/bootstrapped", func(head bootstrappedHead) {
+		if head.Block != lastBlock {
+			lastBlock = head.Block
+			n.markHead()
+		}
+		if status, changed := n.updateSyncState(SyncStateUnsynced); changed {
+			n.reportSyncStatus(status)
+		}
+	})
+	if err != nil {
+		slog.Debug("bootstrap monitor disconnected", "id", n.id, "source", n.Address, "error", err.Error())
+		select {
+		case <-ctx.Done():
+		case <-time.After(constants.NODE_SYNC_CHECK_INTERVAL * time.Second):
+		}
+	}
+}
+
+// runSyncWatcher tracks sync state transitions of the node, unsynced and stuck nodes are skipped by the pool
+func (n *poolNode) runSyncWatcher(ctx context.Context) {
+	ticker := time.NewTicker(constants.NODE_SYNC_CHECK_INTERVAL * time.Second)
+	defer ticker.Stop()
+	for {
+		reported, bootstrapping := n.checkBootstrapped(ctx)
+		if status, changed := n.updateSyncState(reported); changed {
+			n.reportSyncStatus(status)
+		}
+		if bootstrapping {
+			n.followBootstrap(ctx)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+// NodeVersionInfo is octez version and protocols of the node, refreshed periodically and on protocol change
+type NodeVersionInfo struct {
+	Version        string `json:"version,omitempty"`
+	Commit         string `json:"commit,omitempty"`
+	NetworkVersion string `json:"network_version,omitempty"`
+	Protocol       string `json:"protocol,omitempty"`
+	NextProtocol   string `json:"next_protocol,omitempty"`
+	// empty if /config is not allowed
+	HistoryMode string `json:"history_mode,omitempty"`
+	// protocol in adoption period, empty if there is none
+	UpcomingProtocol string `json:"upcoming_protocol,omitempty"`
+	// nil if supported protocols of the node are unknown
+	SupportsUpcomingProtocol *bool      `json:"supports_upcoming_protocol,omitempty"`
+	VersionCheckedAt         *time.Time `json:"version_checked_at,omitempty"`
+}
+
+type nodeVersion struct {
+	Version struct {
+		Major          int             `json:"major"`
+		Minor          int             `json:"minor"`
+		AdditionalInfo json.RawMessage `json:"additional_info"`
+	} `json:"version"`
+	NetworkVersion struct {
+		ChainName            string `json:"chain_name"`
+		DistributedDbVersion int    `json:"distributed_db_version"`
+		P2pVersion           int    `json:"p2p_version"`
+	} `json:"network_version"`
+	CommitInfo struct {
+		CommitHash string `json:"commit_hash"`
+	} `json:"commit_info"`
+}
+
+// String formats version as octez does, e.g. 20.1, 21.0~rc1 or 21.0+dev
+func (v *nodeVersion) String() string {
+	version := fmt.Sprintf("%d.%d", v.Version.Major, v.Version.Minor)
+	var info string
+	if json.Unmarshal(v.Version.AdditionalInfo, &info) == nil {
+		switch info {
+		case "", "release":
+			return version
+		default:
+			return version + "+" + info
+		}
+	}
+	var candidate map[string]int
+	if json.Unmarshal(v.Version.AdditionalInfo, &candidate) == nil {
+		for kind, n := range candidate {
+			return fmt.Sprintf("%s~%s%d", version, kind, n)
+		}
+	}
+	return version
+}
+
+type blockProtocols struct {
+	Protocol     string `json:"protocol"`
+	NextProtocol string `json:"next_protocol"`
+}
+
+type nodeShellConfiguration struct {
+	Shell struct {
+		HistoryMode json.RawMessage `json:"history_mode"`
+	} `json:"shell"`
+}
+
+// parseHistoryMode reads history mode which is either a name or an object like {"rolling": {"additional_cycles": 5}}
+func parseHistoryMode(raw json.RawMessage) string {
+	var mode string
+	if json.Unmarshal(raw, &mode) == nil {
+		return mode
+	}
+	var modes map[string]struct {
+		AdditionalCycles *int `json:"additional_cycles"`
+	}
+	if json.Unmarshal(raw, &modes) == nil {
+		for mode, options := range modes {
+			if options.AdditionalCycles != nil {
+				return fmt.Sprintf("%s:%d", mode, *options.AdditionalCycles)
+			}
+			return mode
+		}
+	}
+	return ""
+}
+
+type votingPeriod struct {
+	VotingPeriod struct {
+		Kind string `json:"kind"`
+	} `json:"voting_period"`
+}
+
+// getUpcomingProtocol returns protocol which is going to be activated, empty if none
+func getUpcomingProtocol(ctx context.Context, client *rpc.Client, protocols blockProtocols) string {
+	if protocols.NextProtocol != "" && protocols.NextProtocol != protocols.Protocol {
+		return protocols.NextProtocol
+	}
+	var period votingPeriod
+	if err := client.Get(ctx, "chains/main/blocks/head/votes/current_period", &period); err != nil || period.VotingPeriod.Kind != "adoption" {
+		return ""
+	}
+	var proposal *string
+	if err := client.Get(ctx, "chains/main/blocks/head/votes/current_proposal", &proposal); err != nil || proposal == nil {
+		return ""
+	}
+	return *proposal
+}
+
+func getNodeVersionInfo(ctx context.Context, client *rpc.Client) (*NodeVersionInfo, error) {
+	var version nodeVersion
+	if err := client.Get(ctx, "version", &version); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	info := &NodeVersionInfo{
+		Version:          version.String(),
+		Commit:           version.CommitInfo.CommitHash,
+		NetworkVersion:   fmt.Sprintf("%s/%d/%d", version.NetworkVersion.ChainName, version.NetworkVersion.DistributedDbVersion, version.NetworkVersion.P2pVersion),
+		VersionCheckedAt: &now,
+	}
+
+	var protocols blockProtocols
+	if err := client.Get(ctx, "chains/main/blocks/head/protocols", &protocols); err == nil {
+		info.Protocol = protocols.Protocol
+		info.NextProtocol = protocols.NextProtocol
+	} else {
+		slog.Debug("failed to get node protocols", "source", client.BaseURL.String(), "error", err.Error())
+	}
+
+	var config nodeShellConfiguration
+	if err := client.Get(ctx, "config", &config); err == nil {
+		info.HistoryMode = parseHistoryMode(config.Shell.HistoryMode)
+	} else {
+		slog.Debug("failed to get node configuration", "source", client.BaseURL.String(), "error", err.Error())
+	}
+
+	info.UpcomingProtocol = getUpcomingProtocol(ctx, client, protocols)
+	if info.UpcomingProtocol != "" {
+		var supportedProtocols []string
+		if err := client.Get(ctx, "protocols", &supportedProtocols); err == nil {
+			supported := slices.Contains(supportedProtocols, info.UpcomingProtocol)
+			info.SupportsUpcomingProtocol = &supported
+		} else {
+			slog.Debug("failed to get node supported protocols", "source", client.BaseURL.String(), "error", err.Error())
+		}
+	}
+	return info, nil
+}
+
+func (n *poolNode) runVersionChecks(ctx context.Context) {
+	ticker := time.NewTicker(constants.NODE_VERSION_CHECK_INTERVAL * time.Second)
+	defer ticker.Stop()
+	for {
+		info, err := getNodeVersionInfo(ctx, n.Client)
+		if err == nil {
+			if info.SupportsUpcomingProtocol != nil && !*info.SupportsUpcomingProtocol {
+				slog.Warn("node does not support upcoming protocol, upgrade it before activation", "id", n.id, "source", n.Address, "version", info.Version, "protocol", info.UpcomingProtocol)
+			}
+			n.reporter.update(func(s *NodeStatus) {
+				s.NodeVersionInfo = info
+			})
+		} else {
+			slog.Debug("failed to get node version", "id", n.id, "source", n.Address, "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.versionRefresh:
+		}
+	}
+}
+
+// runVersionRefreshOnProtocolChange refreshes versions of all nodes when protocol changes
+func runVersionRefreshOnProtocolChange(ctx context.Context) {
+	blockChannelId, blockChannel, err := SubscribeToBlockHeaderEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to block events", "error", err.Error())
+		return
+	}
+	defer UnsubscribeFromBlockHeaderEvents(blockChannelId)
+
+	lastProto := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block, ok := <-blockChannel:
+			if !ok {
+				return
+			}
+			if lastProto != -1 && block.Proto != lastProto {
+				slog.Info("protocol changed, refreshing node versions", "level", block.Level)
+				for _, node := range pool.list() {
+					select {
+					case node.versionRefresh <- struct{}{}:
+					default:
+					}
+				}
+			}
+			lastProto = block.Proto
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tez-capital/tezbake/ami"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/util"
+	"github.com/trilitech/tzgo/rpc"
+	"github.com/trilitech/tzgo/signer/remote"
+	"github.com/trilitech/tzgo/tezos"
+)
+
+type CheckStatus string
+
+const (
+	CheckOk      CheckStatus = "ok"
+	CheckWarning CheckStatus = "warning"
+	CheckFailed  CheckStatus = "failed"
+)
+
+type CheckResult struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+}
+
+type report struct {
+	results []CheckResult
+}
+
+func (r *report) add(status CheckStatus, name string, format string, args ...any) {
+	r.results = append(r.results, CheckResult{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+type doctorNode struct {
+	id string
+	configuration.TezosNode
+	client *rpc.Client
+	synced bool
+}
+
+type bootstrappedStatus struct {
+	Bootstrapped bool   `json:"bootstrapped"`
+	SyncState    string `json:"sync_state"`
+}
+
+func isForbidden(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "status 403")
+}
+
+func checkNodes(ctx context.Context, config *configuration.Runtime, r *report) []*doctorNode {
+	httpClient := &http.Client{Timeout: constants.DOCTOR_HTTP_TIMEOUT_SECONDS * time.Second}
+
+	ids := lo.Keys(config.Nodes)
+	slices.Sort(ids)
+
+	nodes := []*doctorNode{}
+	for _, id := range ids {
+		node := config.Nodes[id]
+		name := fmt.Sprintf("node %s", id)
+		failedStatus := CheckWarning
+		if node.IsEssential {
+			failedStatus = CheckFailed
+		}
+
+		client, err := rpc.NewClient(node.Address, httpClient)
+		if err != nil {
+			r.add(failedStatus, name, "invalid rpc address %s - %s", node.Address, err.Error())
+			continue
+		}
+
+		var status bootstrappedStatus
+		err = client.Get(ctx, "chains/main/is_bootstrapped", &status)
+		switch {
+		case isForbidden(err):
+			r.add(CheckWarning, name, "%s is reachable but restricts is_bootstrapped, sync state unknown", node.Address)
+			nodes = append(nodes, &doctorNode{id: id, TezosNode: node, client: client, synced: true})
+		case err != nil:
+			r.add(failedStatus, name, "%s is not reachable - %s", node.Address, util.TryUnwrapRPCError(err).Error())
+		case !status.Bootstrapped || status.SyncState != "synced":
+			r.add(failedStatus, name, "%s is reachable but not synced (bootstrapped: %t, sync state: %s)", node.Address, status.Bootstrapped, status.SyncState)
+			nodes = append(nodes, &doctorNode{id: id, TezosNode: node, client: client})
+		default:
+			r.add(CheckOk, name, "%s is reachable and synced", node.Address)
+			nodes = append(nodes, &doctorNode{id: id, TezosNode: node, client: client, synced: true})
+		}
+	}
+
+	slices.SortFunc(nodes, func(a, b *doctorNode) int {
+		return b.Priority - a.Priority
+	})
+	return nodes
+}
+
+// attempt runs f with synced nodes ordered by priority until it succeeds
+func attempt[T any](nodes []*doctorNode, f func(client *rpc.Client) (T, error)) (T, error) {
+	var result T
+	err := errors.New("no synced node available")
+	for _, node := range nodes {
+		if !node.synced {
+			continue
+		}
+		result, err = f(node.client)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return result, err
+}
+
+func checkBakers(ctx context.Context, tezbakeConfiguration *configuration.TezbakeModuleConfiguration, nodes []*doctorNode, r *report) {
+	for _, baker := range tezbakeConfiguration.Bakers {
+		name := fmt.Sprintf("baker %s", baker)
+		deactivated, err := attempt(nodes, func(client *rpc.Client) (bool, error) {
+			var deactivated bool
+			err := client.Get(ctx, fmt.Sprintf("chains/main/blocks/head/context/delegates/%s/deactivated", baker), &deactivated)
+			return deactivated, err
+		})
+		switch {
+		case err != nil && strings.Contains(err.Error(), "delegate.not_registered"):
+			r.add(CheckFailed, name, "not registered as delegate")
+		case err != nil:
+			r.add(CheckFailed, name, "failed to check delegate registration - %s", util.TryUnwrapRPCError(err).Error())
+		case deactivated:
+			r.add(CheckFailed, name, "registered as delegate but deactivated")
+		default:
+			r.add(CheckOk, name, "registered as active delegate")
+		}
+	}
+}
+
+func checkSigner(ctx context.Context, tezbakeConfiguration *configuration.TezbakeModuleConfiguration, r *report) {
+	name := "signer"
+	httpClient := &http.Client{Timeout: constants.DOCTOR_HTTP_TIMEOUT_SECONDS * time.Second}
+
+	response, err := httpClient.Get(strings.TrimSuffix(tezbakeConfiguration.SignerUrl, "/") + "/authorized_keys")
+	if err != nil {
+		r.add(CheckFailed, name, "%s does not answer - %s", tezbakeConfiguration.SignerUrl, err.Error())
+		return
+	}
+	response.Body.Close()
+	r.add(CheckOk, name, "%s answers", tezbakeConfiguration.SignerUrl)
+
+	rs, err := remote.New(tezbakeConfiguration.SignerUrl, httpClient)
+	if err != nil {
+		r.add(CheckFailed, name, "failed to create remote signer - %s", err.Error())
+		return
+	}
+
+	for _, baker := range tezbakeConfiguration.Bakers {
+		addr, err := tezos.ParseAddress(baker)
+		if err != nil {
+			continue // reported by configuration validation
+		}
+		if _, err := rs.GetKey(ctx, addr); err != nil {
+			r.add(CheckFailed, name, "does not know key of %s - %s", baker, util.TryUnwrapRPCError(err).Error())
+			continue
+		}
+		r.add(CheckOk, name, "knows key of %s", baker)
+	}
+}
+
+func checkApplications(module string, applications map[string]string, r *report) {
+	ids := lo.Keys(applications)
+	slices.Sort(ids)
+	for _, id := range ids {
+		path := applications[id]
+		if path == "" {
+			continue
+		}
+		name := fmt.Sprintf("%s app %s", module, id)
+		if !ami.IsAppInstalled(path) {
+			r.add(CheckFailed, name, "ami app is not installed in %s", path)
+			continue
+		}
+		r.add(CheckOk, name, "installed in %s", path)
+	}
+}
+
+func checkArcBinary(tezbakeConfiguration *configuration.TezbakeModuleConfiguration, r *report) {
+	name := "arc binary"
+	if tezbakeConfiguration.ArcBinaryPath == "" {
+		r.add(CheckWarning, name, "not found, ledger monitoring disabled")
+		return
+	}
+
+	ver, err := configuration.CheckArcBinaryVersion(tezbakeConfiguration.ArcBinaryPath)
+	switch {
+	case errors.Is(err, constants.ErrArcBinaryVersionTooOld):
+		r.add(CheckFailed, name, "version %s is too old, %s or newer required", ver, constants.MIN_ARC_BINARY_VERSION)
+	case err != nil:
+		r.add(CheckFailed, name, "%s - %s", tezbakeConfiguration.ArcBinaryPath, err.Error())
+	default:
+		r.add(CheckOk, name, "version %s", ver)
+	}
+}
+
+func checkPayoutWallet(ctx context.Context, tezpayConfiguration *configuration.TezpayModuleConfiguration, nodes []*doctorNode, r *report) {
+	name := fmt.Sprintf("payout wallet %s", tezpayConfiguration.PayoutWallet)
+	managerKey, err := attempt(nodes, func(client *rpc.Client) (*string, error) {
+		var managerKey *string
+		err := client.Get(ctx, fmt.Sprintf("chains/main/blocks/head/context/contracts/%s/manager_key", tezpayConfiguration.PayoutWallet), &managerKey)
+		return managerKey, err
+	})
+	switch {
+	case err != nil:
+		r.add(CheckFailed, name, "failed to check manager key - %s", util.TryUnwrapRPCError(err).Error())
+	case managerKey == nil || *managerKey == "":
+		r.add(CheckFailed, name, "key is not revealed")
+	default:
+		r.add(CheckOk, name, "key is revealed")
+	}
+}
+
+// Run checks environment tezpeak is configured for - nodes, bakers, signer, apps and wallets
+func Run(ctx context.Context, config *configuration.Runtime) []CheckResult {
+	r := &report{}
+
+	nodes := checkNodes(ctx, config, r)
+
+	if _, ok := config.Modules[constants.TEZBAKE_MODULE_ID]; ok {
+		tezbakeConfiguration, err := config.LoadTezbakeModuleConfiguration()
+		if tezbakeConfiguration == nil {
+			r.add(CheckFailed, constants.TEZBAKE_MODULE_ID, "failed to load module configuration - %s", err.Error())
+		} else {
+			checkBakers(ctx, tezbakeConfiguration, nodes, r)
+			checkSigner(ctx, tezbakeConfiguration, r)
+			checkApplications(constants.TEZBAKE_MODULE_ID, tezbakeConfiguration.Applications, r)
+			checkArcBinary(tezbakeConfiguration, r)
+		}
+	}
+
+	if _, ok := config.Modules[constants.TEZPAY_MODULE_ID]; ok {
+		tezpayConfiguration, err := config.LoadTezpayModuleConfiguration()
+		if tezpayConfiguration == nil {
+			r.add(CheckFailed, constants.TEZPAY_MODULE_ID, "failed to load module configuration - %s", err.Error())
+		} else {
+			checkApplications(constants.TEZPAY_MODULE_ID, tezpayConfiguration.Applications, r)
+			if _, err := tezos.ParseAddress(tezpayConfiguration.PayoutWallet); err == nil {
+				checkPayoutWallet(ctx, tezpayConfiguration, nodes, r)
+			}
+		}
+	}
+
+	return r.results
+}
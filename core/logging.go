@@ -0,0 +1,42 @@
+package core
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+type setLogLevelParams struct {
+	// e.g. tezbake/rights, empty for the default level
+	Module string `json:"module"`
+	// empty resets module to the default level
+	Level string `json:"level"`
+}
+
+func registerLogEndpoints(app *fiber.Group, config *configuration.Runtime) {
+	canManage := func() bool {
+		return config.Mode == configuration.PrivatePeakMode
+	}
+
+	app.Get("/log-level", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+		return c.JSON(util.GetLogLevels())
+	})
+
+	app.Post("/log-level", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+
+		var params setLogLevelParams
+		if err := c.BodyParser(&params); err != nil {
+			return c.Status(400).SendString("invalid request")
+		}
+		if err := util.SetLogLevel(params.Module, params.Level); err != nil {
+			return c.Status(400).SendString(err.Error())
+		}
+		return c.JSON(util.GetLogLevels())
+	})
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+func registerMetricsEndpoint(app *fiber.Group) {
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		var buffer bytes.Buffer
+		common.WriteRpcMetrics(&buffer)
+		c.Set("Content-Type", "text/plain; version=0.0.4")
+		return c.Send(buffer.Bytes())
+	})
+}
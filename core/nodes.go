@@ -0,0 +1,96 @@
+package core
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type nodeParams struct {
+	Id string `json:"id"`
+	configuration.TezosNode
+}
+
+func nodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, constants.ErrNodeNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, constants.ErrNodeAlreadyExists):
+		return fiber.StatusConflict
+	case errors.Is(err, constants.ErrNodeChanged):
+		return fiber.StatusConflict
+	case errors.Is(err, constants.ErrInvalidNodeUrl), errors.Is(err, constants.ErrInvalidNodes), errors.Is(err, constants.ErrNodeChainMismatch):
+		return fiber.StatusBadRequest
+	default:
+		return fiber.StatusBadGateway // node not reachable
+	}
+}
+
+func registerNodeEndpoints(app *fiber.Group, config *configuration.Runtime) {
+	canManage := func() bool {
+		return config.Mode == configuration.PrivatePeakMode
+	}
+
+	// applied saves the change with ?persist=true, nodes of the runtime configuration are startup nodes only
+	applied := func(c *fiber.Ctx) error {
+		if c.Query("persist") == "true" {
+			if err := config.SaveNodes(common.GetNodes()); err != nil {
+				slog.Error("failed to save nodes", "error", err.Error())
+				return c.Status(500).SendString("node changed, but failed to save configuration")
+			}
+		}
+		return c.JSON(common.ListNodes())
+	}
+
+	app.Get("/nodes", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+		return c.JSON(common.ListNodes())
+	})
+
+	app.Post("/nodes", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+
+		var params nodeParams
+		if err := c.BodyParser(&params); err != nil || params.Id == "" {
+			return c.Status(400).SendString("invalid request")
+		}
+		if err := common.AddNode(c.Context(), params.Id, params.TezosNode); err != nil {
+			return c.Status(nodeErrorStatus(err)).SendString(err.Error())
+		}
+		return applied(c)
+	})
+
+	app.Put("/nodes", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+
+		var params nodeParams
+		if err := c.BodyParser(&params); err != nil || params.Id == "" {
+			return c.Status(400).SendString("invalid request")
+		}
+		if err := common.UpdateNode(c.Context(), params.Id, params.TezosNode); err != nil {
+			return c.Status(nodeErrorStatus(err)).SendString(err.Error())
+		}
+		return applied(c)
+	})
+
+	app.Delete("/nodes", func(c *fiber.Ctx) error {
+		if !canManage() {
+			return c.Status(403).SendString("not allowed")
+		}
+
+		if err := common.RemoveNode(c.Query("id")); err != nil {
+			return c.Status(nodeErrorStatus(err)).SendString(err.Error())
+		}
+		return applied(c)
+	})
+}
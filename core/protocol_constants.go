@@ -0,0 +1,32 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type protocolConstantsResponse struct {
+	Protocol  string          `json:"protocol"`
+	Constants json.RawMessage `json:"constants"`
+}
+
+func registerProtocolConstantsEndpoint(app *fiber.Group) {
+	app.Get("/constants", func(c *fiber.Ctx) error {
+		protocol := c.Query("protocol")
+		if protocol == "" {
+			current, _, raw, ok := common.GetProtocolConstants()
+			if !ok {
+				return c.Status(503).SendString("protocol constants not loaded yet")
+			}
+			return c.JSON(protocolConstantsResponse{Protocol: current, Constants: raw})
+		}
+
+		raw, ok := common.GetProtocolConstantsOf(protocol)
+		if !ok {
+			return c.Status(404).SendString("protocol constants not found")
+		}
+		return c.JSON(protocolConstantsResponse{Protocol: protocol, Constants: raw})
+	})
+}
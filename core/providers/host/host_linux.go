@@ -0,0 +1,116 @@
+//go:build linux
+
+package host
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const isSupported = true
+
+func readLoad() (*LoadStatus, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected /proc/loadavg format %q", string(data))
+	}
+	loads := [3]float64{}
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, err
+		}
+	}
+	return &LoadStatus{
+		Load1:  loads[0],
+		Load5:  loads[1],
+		Load15: loads[2],
+		Cpus:   runtime.NumCPU(),
+	}, nil
+}
+
+// readMeminfo returns values of /proc/meminfo in bytes
+func readMeminfo() (map[string]uint64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// e.g. "MemTotal:       16318912 kB"
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			n *= 1024
+		}
+		result[key] = n
+	}
+	return result, scanner.Err()
+}
+
+func readMemory() (*MemoryStatus, error) {
+	meminfo, err := readMeminfo()
+	if err != nil {
+		return nil, err
+	}
+	total, ok := meminfo["MemTotal"]
+	if !ok {
+		return nil, fmt.Errorf("MemTotal not found in /proc/meminfo")
+	}
+	available, ok := meminfo["MemAvailable"]
+	if !ok {
+		// kernels before 3.14
+		available = meminfo["MemFree"] + meminfo["Buffers"] + meminfo["Cached"]
+	}
+	return &MemoryStatus{
+		Total:           total,
+		Available:       available,
+		UsedPercent:     usedPercent(total, available),
+		SwapTotal:       meminfo["SwapTotal"],
+		SwapFree:        meminfo["SwapFree"],
+		SwapUsedPercent: usedPercent(meminfo["SwapTotal"], meminfo["SwapFree"]),
+	}, nil
+}
+
+func readDisk(path string) (*DiskStatus, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	blockSize := uint64(stat.Bsize)
+	total := stat.Blocks * blockSize
+	// blocks reserved for root are not available to the node
+	available := stat.Bavail * blockSize
+	used := (stat.Blocks - stat.Bfree) * blockSize
+	usable := used + available
+	return &DiskStatus{
+		Path:              path,
+		Total:             total,
+		Available:         available,
+		UsedPercent:       usedPercent(usable, available),
+		InodesTotal:       stat.Files,
+		InodesFree:        stat.Ffree,
+		InodesUsedPercent: usedPercent(stat.Files, stat.Ffree),
+	}, nil
+}
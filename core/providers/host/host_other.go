@@ -0,0 +1,19 @@
+//go:build !linux
+
+package host
+
+import "github.com/tez-capital/tezpeak/constants"
+
+const isSupported = false
+
+func readLoad() (*LoadStatus, error) {
+	return nil, constants.ErrUnsupportedPlatform
+}
+
+func readMemory() (*MemoryStatus, error) {
+	return nil, constants.ErrUnsupportedPlatform
+}
+
+func readDisk(path string) (*DiskStatus, error) {
+	return nil, constants.ErrUnsupportedPlatform
+}
@@ -0,0 +1,233 @@
+package host
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type LoadStatus struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+	Cpus   int     `json:"cpus"`
+	Level  string  `json:"level"`
+}
+
+// MemoryStatus sizes are in bytes
+type MemoryStatus struct {
+	Total           uint64  `json:"total"`
+	Available       uint64  `json:"available"`
+	UsedPercent     float64 `json:"used_percent"`
+	SwapTotal       uint64  `json:"swap_total"`
+	SwapFree        uint64  `json:"swap_free"`
+	SwapUsedPercent float64 `json:"swap_used_percent"`
+	Level           string  `json:"level"`
+}
+
+// DiskStatus is usage of the filesystem holding the path, sizes are in bytes
+type DiskStatus struct {
+	Path              string  `json:"path"`
+	Total             uint64  `json:"total"`
+	Available         uint64  `json:"available"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+	Level             string  `json:"level"`
+}
+
+type ClockStatus struct {
+	// milliseconds, estimated from arrival of recent blocks
+	Offset int64  `json:"offset"`
+	Level  string `json:"level"`
+}
+
+type Status struct {
+	Load   *LoadStatus   `json:"load,omitempty"`
+	Memory *MemoryStatus `json:"memory,omitempty"`
+	// application id -> disk status
+	Disks     map[string]DiskStatus `json:"disks"`
+	Clock     *ClockStatus          `json:"clock,omitempty"`
+	Level     string                `json:"level"`
+	Timestamp int64                 `json:"timestamp"`
+}
+
+type StatusUpdate struct {
+	Status *Status
+}
+
+func (statusUpdate *StatusUpdate) GetId() string {
+	return "host"
+}
+
+func (statusUpdate *StatusUpdate) GetData() any {
+	return statusUpdate.Status
+}
+
+func getLevel(value, warningThreshold, errorThreshold float64) string {
+	switch {
+	case value >= errorThreshold:
+		return "error"
+	case value >= warningThreshold:
+		return "warning"
+	default:
+		return "ok"
+	}
+}
+
+func worstLevel(levels ...string) string {
+	result := "ok"
+	for _, level := range levels {
+		switch {
+		case level == "error":
+			return level
+		case level == "warning":
+			result = level
+		}
+	}
+	return result
+}
+
+func usedPercent(total, free uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(total-free) * 100 / float64(total)
+}
+
+// getDiskPath prefers data directory of the application, e.g. node/data holding the chain
+func getDiskPath(applicationPath string) string {
+	dataPath := filepath.Join(applicationPath, "data")
+	if info, err := os.Stat(dataPath); err == nil && info.IsDir() {
+		return dataPath
+	}
+	return applicationPath
+}
+
+func collectStatus(applications map[string]string, preferences configuration.HostPreferences) *Status {
+	status := &Status{
+		Disks:     map[string]DiskStatus{},
+		Timestamp: time.Now().Unix(),
+	}
+	levels := []string{}
+
+	if load, err := readLoad(); err == nil {
+		load.Level = getLevel(load.Load1/float64(max(load.Cpus, 1)), preferences.LoadWarningThreshold, preferences.LoadErrorThreshold)
+		status.Load = load
+		levels = append(levels, load.Level)
+	} else {
+		slog.Debug("failed to read load", "error", err.Error())
+	}
+
+	if memory, err := readMemory(); err == nil {
+		memory.Level = worstLevel(
+			getLevel(memory.UsedPercent, preferences.MemoryWarningThreshold, preferences.MemoryErrorThreshold),
+			getLevel(memory.SwapUsedPercent, preferences.SwapWarningThreshold, preferences.SwapErrorThreshold),
+		)
+		status.Memory = memory
+		levels = append(levels, memory.Level)
+	} else {
+		slog.Debug("failed to read memory", "error", err.Error())
+	}
+
+	for id, path := range applications {
+		if path == "" {
+			continue
+		}
+		disk, err := readDisk(getDiskPath(path))
+		if err != nil {
+			slog.Debug("failed to read disk usage", "application", id, "path", path, "error", err.Error())
+			continue
+		}
+		disk.Level = worstLevel(
+			getLevel(disk.UsedPercent, preferences.DiskWarningThreshold, preferences.DiskErrorThreshold),
+			getLevel(disk.InodesUsedPercent, preferences.InodeWarningThreshold, preferences.InodeErrorThreshold),
+		)
+		status.Disks[id] = *disk
+		levels = append(levels, disk.Level)
+	}
+
+	if offset, ok := common.GetClockOffset(); ok {
+		absOffset := float64(max(offset, -offset))
+		status.Clock = &ClockStatus{
+			Offset: offset,
+			Level:  getLevel(absOffset, preferences.ClockOffsetWarningThreshold, preferences.ClockOffsetErrorThreshold),
+		}
+		levels = append(levels, status.Clock.Level)
+	}
+
+	status.Level = worstLevel(levels...)
+	return status
+}
+
+// logLevelChanges reports resources which changed their level since the last check
+func logLevelChanges(previous, current map[string]string) {
+	for resource, level := range current {
+		if previous[resource] == level {
+			continue
+		}
+		switch level {
+		case "ok":
+			if previous[resource] != "" {
+				slog.Info("host resource recovered", "resource", resource)
+			}
+		default:
+			slog.Warn("host resource above threshold", "resource", resource, "level", level)
+		}
+	}
+}
+
+func getResourceLevels(status *Status) map[string]string {
+	levels := map[string]string{}
+	if status.Load != nil {
+		levels["load"] = status.Load.Level
+	}
+	if status.Memory != nil {
+		levels["memory"] = status.Memory.Level
+	}
+	for id, disk := range status.Disks {
+		levels["disk:"+id] = disk.Level
+	}
+	if status.Clock != nil {
+		levels["clock"] = status.Clock.Level
+	}
+	return levels
+}
+
+func SetupModule(ctx context.Context, configuration *configuration.HostModuleConfiguration, app *fiber.Group, statusChannel chan<- common.StatusUpdate) error {
+	if !isSupported {
+		slog.Warn("host module is supported only on linux, skipping")
+		return nil
+	}
+
+	applications := maps.Clone(configuration.Applications)
+	go func() {
+		ticker := time.NewTicker(constants.HOST_STATUS_INTERVAL * time.Second)
+		defer ticker.Stop()
+		levels := map[string]string{}
+		for {
+			status := collectStatus(applications, configuration.Preferences)
+			currentLevels := getResourceLevels(status)
+			logLevelChanges(levels, currentLevels)
+			levels = currentLevels
+			statusChannel <- &StatusUpdate{Status: status}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package tezbake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+// cycleRights are rights of the bakers in a cycle fetched at once, rights do not change once the cycle is known
+type cycleRights struct {
+	Cycle  int64    `json:"cycle"`
+	Bakers []string `json:"bakers"`
+	// level -> baker -> round 0 baking slots
+	Baking map[int64]map[string]int `json:"baking"`
+	// highest fetched baking round and level -> baker -> baking rounds up to it
+	MaxRound     int64                        `json:"max_round"`
+	BakingRounds map[int64]map[string][]int64 `json:"baking_rounds"`
+	// level -> baker -> attesting power
+	Attestations map[int64]map[string]int `json:"attestations"`
+}
+
+func (r *cycleRights) covers(bakers []string, maxRound int64) bool {
+	if r.MaxRound < maxRound || r.BakingRounds == nil {
+		return false // cached before rounds were tracked or with fewer rounds
+	}
+	for _, baker := range bakers {
+		if !slices.Contains(r.Bakers, baker) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockRights derives rights of the bakers for the level
+func (r *cycleRights) blockRights(level int64, bakers []string, maxRound int64) BlockRights {
+	rounds := map[string][]int64{}
+	for baker, bakerRounds := range r.BakingRounds[level] {
+		for _, round := range bakerRounds {
+			if round <= maxRound {
+				rounds[baker] = append(rounds[baker], round)
+			}
+		}
+	}
+	return newBlockRights(level, bakers, rounds, r.Attestations[level])
+}
+
+// cycleRightsCache keeps rights of recent cycles in memory and on disk
+type cycleRightsCache struct {
+	mtx     sync.Mutex
+	dataDir string
+	// highest baking round rights are tracked for
+	maxRound int64
+	cycles   map[int64]*cycleRights
+	// cycle -> time of the last failed fetch, failed cycles are not fetched again for a while
+	failedAt map[int64]time.Time
+	// level -> baker -> [baked, attested] of checked rights
+	realized map[int64]map[string][]int
+	// serializes fetches so the same cycle is not fetched twice
+	fetchMtx sync.Mutex
+}
+
+func newCycleRightsCache(dataDir string, maxRound int64) *cycleRightsCache {
+	return &cycleRightsCache{
+		dataDir:  dataDir,
+		maxRound: maxRound,
+		cycles:   map[int64]*cycleRights{},
+		failedAt: map[int64]time.Time{},
+		realized: map[int64]map[string][]int{},
+	}
+}
+
+// getDir returns cache directory of the active chain, there is none before the network is detected
+func (c *cycleRightsCache) getDir() (string, bool) {
+	chainDir, ok := common.GetChainDataDir(c.dataDir)
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(chainDir, constants.RIGHTS_CACHE_DIR), true
+}
+
+func (c *cycleRightsCache) getFilePath(cycle int64) (string, bool) {
+	dir, ok := c.getDir()
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, fmt.Sprintf("%d.json", cycle)), true
+}
+
+func (c *cycleRightsCache) load(cycle int64) (*cycleRights, bool) {
+	path, ok := c.getFilePath(cycle)
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var rights cycleRights
+	if err := json.Unmarshal(data, &rights); err != nil || rights.Cycle != cycle {
+		slog.Warn("ignoring invalid cached cycle rights", "cycle", cycle)
+		return nil, false
+	}
+	return &rights, true
+}
+
+func (c *cycleRightsCache) save(rights *cycleRights) {
+	path, ok := c.getFilePath(rights.Cycle)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(rights)
+	if err == nil {
+		err = util.WriteFileAtomic(path, data)
+	}
+	if err != nil {
+		slog.Warn("failed to cache cycle rights", "cycle", rights.Cycle, "error", err.Error())
+	}
+}
+
+func (c *cycleRightsCache) cached(cycle int64, bakers []string) (*cycleRights, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	rights, ok := c.cycles[cycle]
+	if !ok {
+		if rights, ok = c.load(cycle); ok {
+			c.cycles[cycle] = rights
+		}
+	}
+	if !ok || !rights.covers(bakers, c.maxRound) {
+		return nil, false
+	}
+	return rights, true
+}
+
+// get returns rights of the bakers in the cycle, fetching them if not cached yet
+func (c *cycleRightsCache) get(ctx context.Context, cycle int64, bakers []string) (*cycleRights, error) {
+	if rights, ok := c.cached(cycle, bakers); ok {
+		return rights, nil
+	}
+
+	c.fetchMtx.Lock()
+	defer c.fetchMtx.Unlock()
+	if rights, ok := c.cached(cycle, bakers); ok {
+		return rights, nil // fetched meanwhile
+	}
+
+	c.mtx.Lock()
+	failedAt, failed := c.failedAt[cycle]
+	c.mtx.Unlock()
+	if failed && time.Since(failedAt) < constants.RIGHTS_CACHE_RETRY_INTERVAL*time.Second {
+		return nil, fmt.Errorf("fetching rights of cycle %d failed recently", cycle)
+	}
+
+	rights, err := fetchCycleRights(ctx, cycle, bakers, c.maxRound)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err != nil {
+		c.failedAt[cycle] = time.Now()
+		return nil, err
+	}
+	delete(c.failedAt, cycle)
+	c.save(rights)
+	c.cycles[cycle] = rights
+	return rights, nil
+}
+
+// prune drops cycles older than the oldest cycle to keep
+func (c *cycleRightsCache) prune(oldest int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for cycle := range c.cycles {
+		if cycle < oldest {
+			delete(c.cycles, cycle)
+		}
+	}
+	for cycle := range c.failedAt {
+		if cycle < oldest {
+			delete(c.failedAt, cycle)
+		}
+	}
+
+	dir, ok := c.getDir()
+	if !ok {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		cycle, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), ".json"), 10, 64)
+		if err != nil || cycle >= oldest {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			slog.Warn("failed to remove cached cycle rights", "cycle", cycle, "error", err.Error())
+		}
+	}
+}
+
+// getBlockRights derives rights for the level from rights of its cycle
+func (c *cycleRightsCache) getBlockRights(ctx context.Context, level int64, bakers []string) (BlockRights, error) {
+	// rights of the previous level are reported at the level, see getBlockRightsFor
+	cycle, _, ok := common.GetCycleOfLevel(level - 1)
+	if !ok {
+		return BlockRights{}, errors.New("cycle of the level not known yet")
+	}
+	rights, err := c.get(ctx, cycle, bakers)
+	if err != nil {
+		return BlockRights{}, err
+	}
+	blockRights := rights.blockRights(level-1, bakers, c.maxRound)
+	blockRights.Level = level
+	return blockRights, nil
+}
+
+func fetchCycleRights(ctx context.Context, cycle int64, bakers []string, maxRound int64) (*cycleRights, error) {
+	rights := &cycleRights{
+		Cycle:        cycle,
+		Bakers:       slices.Clone(bakers),
+		Baking:       map[int64]map[string]int{},
+		MaxRound:     maxRound,
+		BakingRounds: map[int64]map[string][]int64{},
+		Attestations: map[int64]map[string]int{},
+	}
+	if len(bakers) == 0 {
+		return rights, nil // without delegates the node would return rights of everyone
+	}
+
+	query := url.Values{}
+	query.Set("cycle", strconv.FormatInt(cycle, 10))
+	for _, baker := range bakers {
+		query.Add("delegate", baker)
+	}
+
+	var bakingRights blockBakingRights
+	var attestationRights []blockAttestationRights
+	var bakingRightsErr, attestationRightsErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		rightsUrl := fmt.Sprintf("chains/main/blocks/head/helpers/baking_rights?max_round=%d&%s", maxRound, query.Encode())
+		bakingRights, bakingRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (blockBakingRights, error) {
+			bakingRights := blockBakingRights{}
+			err := client.Get(ctx, rightsUrl, &bakingRights)
+			return bakingRights, err
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		rightsUrl := "chains/main/blocks/head/helpers/attestation_rights?" + query.Encode()
+		attestationRights, attestationRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) ([]blockAttestationRights, error) {
+			attestationRights := []blockAttestationRights{}
+			err := client.Get(ctx, rightsUrl, &attestationRights)
+			return attestationRights, err
+		})
+	}()
+	wg.Wait()
+	if err := errors.Join(bakingRightsErr, attestationRightsErr); err != nil {
+		return nil, err
+	}
+
+	add := func(rights map[int64]map[string]int, level int64, baker string, value int) {
+		if _, ok := rights[level]; !ok {
+			rights[level] = map[string]int{}
+		}
+		rights[level][baker] += value
+	}
+	for _, right := range bakingRights {
+		if right.Round > maxRound {
+			continue
+		}
+		if right.Round == 0 {
+			add(rights.Baking, right.Level, right.Delegate, 1)
+		}
+		if _, ok := rights.BakingRounds[right.Level]; !ok {
+			rights.BakingRounds[right.Level] = map[string][]int64{}
+		}
+		rights.BakingRounds[right.Level][right.Delegate] = append(rights.BakingRounds[right.Level][right.Delegate], right.Round)
+	}
+	for _, levelRights := range attestationRights {
+		for _, right := range levelRights.Delegates {
+			add(rights.Attestations, levelRights.Level, right.Delegate, right.AttestationPower)
+		}
+	}
+	slog.Debug("cycle rights fetched", "cycle", cycle, "bakers", len(bakers))
+	return rights, nil
+}
+
+// runCycleRightsPrefetch fetches rights of the next cycle ahead and prunes old cycles
+func (c *cycleRightsCache) runCycleRightsPrefetch(ctx context.Context, bakers []string) {
+	cycleChannelId, cycleChannel, err := common.SubscribeToCycleEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to cycle events", "error", err.Error())
+		return
+	}
+	defer common.UnsubscribeFromCycleEvents(cycleChannelId)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-cycleChannel:
+			if !ok {
+				return
+			}
+			switch event.Kind {
+			case common.NewCycleEvent:
+				c.prune(event.Cycle - constants.RIGHTS_CACHE_PAST_CYCLES)
+			case common.CycleEndingEvent:
+				if _, err := c.get(ctx, event.Cycle+1, bakers); err != nil {
+					slog.Warn("failed to prefetch cycle rights", "cycle", event.Cycle+1, "error", err.Error())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package tezbake
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type RightsSummary struct {
+	Total int `json:"total"`
+	// rights at levels already baked
+	Passed   int `json:"passed"`
+	Realized int `json:"realized"`
+	Missed   int `json:"missed"`
+	// passed rights realization of which was not checked, e.g. before tezpeak started
+	Unchecked int `json:"unchecked"`
+	// realized of checked rights in percent, missing without checked rights
+	Participation *float64 `json:"participation,omitempty"`
+}
+
+func (s *RightsSummary) add(value int, passed bool, realized int, checked bool) {
+	s.Total += value
+	if !passed {
+		return
+	}
+	s.Passed += value
+	switch {
+	case !checked:
+		s.Unchecked += value
+	case realized > 0:
+		s.Realized += value
+	default:
+		s.Missed += value
+	}
+}
+
+func (s *RightsSummary) updateParticipation() {
+	if checked := s.Realized + s.Missed; checked > 0 {
+		participation := float64(s.Realized) * 100 / float64(checked)
+		s.Participation = &participation
+	}
+}
+
+// BakerCycleRights are baking slots and attestation power of the baker in a cycle
+type BakerCycleRights struct {
+	Blocks       RightsSummary `json:"blocks"`
+	Attestations RightsSummary `json:"attestations"`
+}
+
+type CycleRights struct {
+	Cycle      int64                        `json:"cycle"`
+	FirstLevel int64                        `json:"first_level"`
+	LastLevel  int64                        `json:"last_level"`
+	Bakers     map[string]*BakerCycleRights `json:"bakers"`
+}
+
+type CycleRightsStatus struct {
+	Level int64 `json:"level"`
+	// current cycle followed by cycles with already known rights
+	Cycles []CycleRights `json:"cycles"`
+}
+
+type CycleRightsStatusUpdate struct {
+	CycleRightsStatus
+}
+
+func (s *CycleRightsStatusUpdate) GetId() string {
+	return "cycle_rights"
+}
+
+func (s *CycleRightsStatusUpdate) GetData() any {
+	return s.CycleRightsStatus
+}
+
+// recordRealized keeps realization of checked block rights for cycle summaries
+func (c *cycleRightsCache) recordRealized(rights []BlockRights) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, right := range rights {
+		if !right.RealizedChecked {
+			continue
+		}
+		// rights of the previous level are reported at the level, see getBlockRightsFor
+		realized := map[string][]int{}
+		for baker, r := range right.Rights {
+			if len(r) > 3 {
+				realized[baker] = []int{r[2], r[3]}
+			}
+		}
+		c.realized[right.Level-1] = realized
+	}
+}
+
+// summarize builds summary of the bakers rights in the cycle, levels up to the head level are passed
+func (c *cycleRightsCache) summarize(rights *cycleRights, bakers []string, headLevel int64) CycleRights {
+	firstLevel, lastLevel, _ := common.GetCycleLevels(rights.Cycle)
+	summary := CycleRights{
+		Cycle:      rights.Cycle,
+		FirstLevel: firstLevel,
+		LastLevel:  lastLevel,
+		Bakers:     map[string]*BakerCycleRights{},
+	}
+	for _, baker := range bakers {
+		summary.Bakers[baker] = &BakerCycleRights{}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, baker := range bakers {
+		bakerSummary := summary.Bakers[baker]
+		// realized rights of the last level are checked in the next block
+		for level, levelRights := range rights.Baking {
+			realized, checked := c.realized[level][baker]
+			bakerSummary.Blocks.add(levelRights[baker], level < headLevel, realizedAt(realized, 0), checked)
+		}
+		for level, levelRights := range rights.Attestations {
+			realized, checked := c.realized[level][baker]
+			bakerSummary.Attestations.add(levelRights[baker], level < headLevel, realizedAt(realized, 1), checked)
+		}
+		bakerSummary.Blocks.updateParticipation()
+		bakerSummary.Attestations.updateParticipation()
+	}
+	return summary
+}
+
+func realizedAt(realized []int, i int) int {
+	if len(realized) <= i {
+		return 0
+	}
+	return realized[i]
+}
+
+// pruneRealized drops realization of levels before the level
+func (c *cycleRightsCache) pruneRealized(level int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for l := range c.realized {
+		if l < level {
+			delete(c.realized, l)
+		}
+	}
+}
+
+func (c *cycleRightsCache) getCycleRightsStatus(ctx context.Context, bakers []string, headLevel int64) (CycleRightsStatus, bool) {
+	cycle, firstLevel, ok := common.GetCycleOfLevel(headLevel)
+	if !ok {
+		return CycleRightsStatus{}, false
+	}
+	_, protocolConstants, _, ok := common.GetProtocolConstants()
+	if !ok {
+		return CycleRightsStatus{}, false
+	}
+	c.pruneRealized(firstLevel - 1)
+
+	status := CycleRightsStatus{
+		Level:  headLevel,
+		Cycles: []CycleRights{},
+	}
+	for i := cycle; i <= cycle+protocolConstants.ConsensusRightsDelay; i++ {
+		rights, err := c.get(ctx, i, bakers)
+		if err != nil {
+			slog.Debug("failed to get cycle rights", "cycle", i, "error", err.Error())
+			break
+		}
+		status.Cycles = append(status.Cycles, c.summarize(rights, bakers, headLevel))
+	}
+	return status, true
+}
+
+func startCycleRightsStatusProvider(ctx context.Context, bakers []string, rightsCache *cycleRightsCache, statusChannel chan<- common.StatusUpdate) {
+	blockChannelId, blockChannel, err := common.SubscribeToBlockHeaderEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to block events", "error", err.Error())
+		return
+	}
+
+	go func() {
+		defer common.UnsubscribeFromBlockHeaderEvents(blockChannelId)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case block, ok := <-blockChannel:
+				if !ok {
+					return
+				}
+				status, ok := rightsCache.getCycleRightsStatus(ctx, bakers, block.Level)
+				if !ok {
+					continue // cycle not known yet
+				}
+				statusChannel <- &CycleRightsStatusUpdate{status}
+			}
+		}
+	}()
+}
+
+func registerCycleRightsEndpoint(app *fiber.Group, bakers []string, rightsCache *cycleRightsCache) {
+	app.Get("/tezbake/cycles/:cycle/rights", func(c *fiber.Ctx) error {
+		cycle, err := c.ParamsInt("cycle")
+		if err != nil || cycle < 0 {
+			return c.Status(400).SendString("invalid cycle")
+		}
+		headLevel := common.GetHeadLevel()
+		currentCycle, _, ok := common.GetCycleOfLevel(headLevel)
+		if !ok {
+			return c.Status(503).SendString("cycle not known yet")
+		}
+		_, protocolConstants, _, ok := common.GetProtocolConstants()
+		if !ok {
+			return c.Status(503).SendString("protocol constants not known yet")
+		}
+
+		// only cycles of the cache window are fetched, others are served if they are still cached
+		var rights *cycleRights
+		if int64(cycle) >= currentCycle-constants.RIGHTS_CACHE_PAST_CYCLES && int64(cycle) <= currentCycle+protocolConstants.ConsensusRightsDelay {
+			rights, err = rightsCache.get(c.Context(), int64(cycle), bakers)
+			if err != nil {
+				return c.Status(502).SendString(err.Error())
+			}
+		} else if rights, ok = rightsCache.cached(int64(cycle), bakers); !ok {
+			return c.Status(404).SendString("cycle rights not available")
+		}
+		return c.JSON(rightsCache.summarize(rights, bakers, headLevel))
+	})
+}
@@ -0,0 +1,248 @@
+package tezbake
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+type UpcomingRight struct {
+	Level int64 `json:"level"`
+	// estimated time assuming blocks at round 0
+	Eta time.Time `json:"eta"`
+}
+
+// MaintenanceWindow is a period without rights, from the last right before it to the first right after it
+type MaintenanceWindow struct {
+	FromLevel int64     `json:"from_level"`
+	ToLevel   int64     `json:"to_level"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Duration  int64     `json:"duration"` // seconds
+}
+
+type BakerMaintenanceStatus struct {
+	NextBaking      *UpcomingRight `json:"next_baking,omitempty"`
+	NextAttestation *UpcomingRight `json:"next_attestation,omitempty"`
+	// longest window without rights of the baker within the horizon
+	LongestFreeWindow *MaintenanceWindow `json:"longest_free_window,omitempty"`
+}
+
+type MaintenanceStatus struct {
+	Level   int64                              `json:"level"`
+	Horizon int64                              `json:"horizon"` // hours covered by known rights
+	Bakers  map[string]*BakerMaintenanceStatus `json:"bakers"`
+	// longest window without rights of any baker, bakers are usually restarted together
+	LongestFreeWindow *MaintenanceWindow `json:"longest_free_window,omitempty"`
+}
+
+type MaintenanceStatusUpdate struct {
+	MaintenanceStatus
+}
+
+func (s *MaintenanceStatusUpdate) GetId() string {
+	return "maintenance"
+}
+
+func (s *MaintenanceStatusUpdate) GetData() any {
+	return s.MaintenanceStatus
+}
+
+// findLongestFreeWindow finds longest run of levels without rights after the head up to the last level,
+// window after the last right ends at the last level
+func findLongestFreeWindow(busy map[int64]bool, headLevel int64, lastLevel int64) (int64, int64) {
+	bestFrom, bestTo := headLevel, headLevel
+	from := headLevel
+	for level := headLevel + 1; level <= lastLevel; level++ {
+		if !busy[level] && level != lastLevel {
+			continue
+		}
+		if level-from > bestTo-bestFrom {
+			bestFrom, bestTo = from, level
+		}
+		from = level
+	}
+	return bestFrom, bestTo
+}
+
+type maintenanceProvider struct {
+	bakers      []string
+	rightsCache *cycleRightsCache
+	horizon     int64 // hours
+
+	mtx           sync.Mutex
+	headLevel     int64
+	headTimestamp time.Time
+}
+
+// getMaintenanceStatus computes next rights and free windows within the horizon from the cycle rights
+func (p *maintenanceProvider) getMaintenanceStatus(ctx context.Context, horizon int64) (MaintenanceStatus, bool) {
+	p.mtx.Lock()
+	headLevel, headTimestamp := p.headLevel, p.headTimestamp
+	p.mtx.Unlock()
+
+	_, protocolConstants, _, ok := common.GetProtocolConstants()
+	if !ok || protocolConstants.MinimalBlockDelay <= 0 || headLevel == 0 {
+		return MaintenanceStatus{}, false
+	}
+	firstCycle, _, ok := common.GetCycleOfLevel(headLevel + 1)
+	if !ok {
+		return MaintenanceStatus{}, false
+	}
+	lastLevel := headLevel + horizon*3600/protocolConstants.MinimalBlockDelay
+	lastCycle, _, _ := common.GetCycleOfLevel(lastLevel)
+	if lastCycle > firstCycle+protocolConstants.ConsensusRightsDelay {
+		// rights of later cycles are not known yet
+		lastCycle = firstCycle + protocolConstants.ConsensusRightsDelay
+		_, lastCycleLevel, _ := common.GetCycleLevels(lastCycle)
+		lastLevel = min(lastLevel, lastCycleLevel)
+	}
+
+	baking := map[string]map[int64]bool{}
+	attestations := map[string]map[int64]bool{}
+	for _, baker := range p.bakers {
+		baking[baker] = map[int64]bool{}
+		attestations[baker] = map[int64]bool{}
+	}
+	for cycle := firstCycle; cycle <= lastCycle; cycle++ {
+		rights, err := p.rightsCache.get(ctx, cycle, p.bakers)
+		if err != nil {
+			slog.Debug("failed to get cycle rights", "cycle", cycle, "error", err.Error())
+			if cycle == firstCycle {
+				return MaintenanceStatus{}, false // nothing to compute windows from
+			}
+			// the horizon ends before the cycle
+			_, lastKnownLevel, _ := common.GetCycleLevels(cycle - 1)
+			lastLevel = min(lastLevel, lastKnownLevel)
+			break
+		}
+		for _, baker := range p.bakers {
+			for level, levelRights := range rights.Baking {
+				if levelRights[baker] > 0 {
+					baking[baker][level] = true
+				}
+			}
+			for level, levelRights := range rights.Attestations {
+				if levelRights[baker] > 0 {
+					attestations[baker][level] = true
+				}
+			}
+		}
+	}
+
+	estimate := func(level int64) time.Time {
+		eta, _ := common.EstimateLevelTime(headLevel, headTimestamp, level)
+		return eta
+	}
+	nextRight := func(rights map[int64]bool) *UpcomingRight {
+		for level := headLevel + 1; level <= lastLevel; level++ {
+			if rights[level] {
+				return &UpcomingRight{Level: level, Eta: estimate(level)}
+			}
+		}
+		return nil
+	}
+	window := func(busy map[int64]bool) *MaintenanceWindow {
+		from, to := findLongestFreeWindow(busy, headLevel, lastLevel)
+		if to <= from {
+			return nil
+		}
+		return &MaintenanceWindow{
+			FromLevel: from,
+			ToLevel:   to,
+			From:      estimate(from),
+			To:        estimate(to),
+			Duration:  (to - from) * protocolConstants.MinimalBlockDelay,
+		}
+	}
+
+	status := MaintenanceStatus{
+		Level: headLevel,
+		// shorter than requested if rights of later cycles are not known
+		Horizon: min(horizon, (lastLevel-headLevel)*protocolConstants.MinimalBlockDelay/3600),
+		Bakers:  map[string]*BakerMaintenanceStatus{},
+	}
+	anyBusy := map[int64]bool{}
+	for _, baker := range p.bakers {
+		busy := map[int64]bool{}
+		for level := range baking[baker] {
+			busy[level] = true
+			anyBusy[level] = true
+		}
+		for level := range attestations[baker] {
+			busy[level] = true
+			anyBusy[level] = true
+		}
+		status.Bakers[baker] = &BakerMaintenanceStatus{
+			NextBaking:        nextRight(baking[baker]),
+			NextAttestation:   nextRight(attestations[baker]),
+			LongestFreeWindow: window(busy),
+		}
+	}
+	status.LongestFreeWindow = window(anyBusy)
+	return status, true
+}
+
+func (p *maintenanceProvider) start(ctx context.Context, statusChannel chan<- common.StatusUpdate) {
+	blockChannelId, blockChannel, err := common.SubscribeToBlockHeaderEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to block events", "error", err.Error())
+		return
+	}
+
+	go func() {
+		defer common.UnsubscribeFromBlockHeaderEvents(blockChannelId)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case block, ok := <-blockChannel:
+				if !ok {
+					return
+				}
+				p.mtx.Lock()
+				if block.Level <= p.headLevel {
+					p.mtx.Unlock()
+					continue
+				}
+				p.headLevel, p.headTimestamp = block.Level, block.Timestamp
+				p.mtx.Unlock()
+
+				status, ok := p.getMaintenanceStatus(ctx, p.horizon)
+				if !ok {
+					continue // cycle or constants not known yet
+				}
+				statusChannel <- &MaintenanceStatusUpdate{status}
+			}
+		}
+	}()
+}
+
+func (p *maintenanceProvider) registerEndpoint(app *fiber.Group) {
+	app.Get("/tezbake/maintenance-windows", func(c *fiber.Ctx) error {
+		horizon := c.QueryInt("hours", int(p.horizon))
+		if horizon <= 0 {
+			return c.Status(400).SendString("invalid hours")
+		}
+		status, ok := p.getMaintenanceStatus(c.Context(), int64(horizon))
+		if !ok {
+			return c.Status(503).SendString("rights not known yet")
+		}
+		return c.JSON(status)
+	})
+}
+
+func setupMaintenanceProvider(ctx context.Context, app *fiber.Group, bakers []string, horizon int64, rightsCache *cycleRightsCache, statusChannel chan<- common.StatusUpdate) {
+	provider := &maintenanceProvider{
+		bakers:      bakers,
+		rightsCache: rightsCache,
+		horizon:     horizon,
+	}
+	provider.registerEndpoint(app)
+	provider.start(ctx, statusChannel)
+}
@@ -0,0 +1,376 @@
+package tezbake
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+const (
+	LateAttestation    = "late"
+	MissingAttestation = "missing"
+)
+
+// MissContext describes circumstances of a missed right
+type MissContext struct {
+	Block       bool `json:"block"`
+	Attestation bool `json:"attestation"`
+	// missed block as seen by tezpeak
+	Round           int    `json:"round"`
+	Proposer        string `json:"proposer,omitempty"`
+	PayloadProducer string `json:"payload_producer,omitempty"`
+	// node id -> milliseconds between block timestamp and its arrival to the node
+	ArrivalDelays map[string]int64 `json:"arrival_delays,omitempty"`
+	HeadArrivedAt *time.Time       `json:"head_arrived_at,omitempty"`
+	// late if the baker preattested the missed block or its attestation was included on recheck, missing otherwise
+	AttestationStatus string `json:"attestation_status,omitempty"`
+	// signer services not running and disconnected ledger wallets at the time of the check
+	SignerServicesDown  []string `json:"signer_services_down,omitempty"`
+	DisconnectedLedgers []string `json:"disconnected_ledgers,omitempty"`
+}
+
+// RealizedRights is realization check of the baker rights at a level, BlockRights report them at the next level
+type RealizedRights struct {
+	Level     int64        `json:"level"`
+	Baker     string       `json:"baker"`
+	Rights    []int        `json:"rights"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Miss      *MissContext `json:"miss,omitempty"`
+}
+
+func isMiss(rights []int) (bool, bool) {
+	if len(rights) < 4 {
+		return false, false
+	}
+	return rights[0] > 0 && rights[2] == 0, rights[1] > 0 && rights[3] == 0
+}
+
+// missEnvironment is state of the baker setup when rights were checked
+type missEnvironment struct {
+	signerServicesDown  []string
+	disconnectedLedgers []string
+}
+
+func getMissEnvironment(status *Status) missEnvironment {
+	environment := missEnvironment{}
+	if services, ok := status.Services.Applications["signer"]; ok && services != nil {
+		for name, service := range *services {
+			if service.Status != "running" {
+				environment.signerServicesDown = append(environment.signerServicesDown, name)
+			}
+		}
+		slices.Sort(environment.signerServicesDown)
+	}
+	wallets := WalletsStatus(status.Wallets)
+	environment.disconnectedLedgers, _ = wallets.DisconnectedLedgerWallets()
+	return environment
+}
+
+type missObservation struct {
+	rights      []BlockRights
+	headLevel   int64
+	environment missEnvironment
+}
+
+// missHistory persists realization checks per cycle in json lines, later lines replace earlier for the same level and baker
+type missHistory struct {
+	dataDir      string
+	observations chan missObservation
+
+	mtx sync.Mutex
+	// level -> baker -> recorded rights
+	recorded map[int64]map[string][]int
+}
+
+func newMissHistory(dataDir string) *missHistory {
+	return &missHistory{
+		dataDir:      dataDir,
+		observations: make(chan missObservation, 10),
+		recorded:     map[int64]map[string][]int{},
+	}
+}
+
+// observe queues checked rights for recording, observations are dropped if recording falls behind
+func (h *missHistory) observe(rights []BlockRights, headLevel int64, environment missEnvironment) {
+	select {
+	case h.observations <- missObservation{rights: rights, headLevel: headLevel, environment: environment}:
+	default:
+	}
+}
+
+// getDir returns history directory of the active chain, there is none before the network is detected
+func (h *missHistory) getDir() (string, bool) {
+	chainDir, ok := common.GetChainDataDir(h.dataDir)
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(chainDir, constants.MISS_HISTORY_DIR), true
+}
+
+func (h *missHistory) getFilePath(cycle int64) (string, bool) {
+	dir, ok := h.getDir()
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, fmt.Sprintf("%d.jsonl", cycle)), true
+}
+
+func (h *missHistory) append(cycle int64, records []RealizedRights) error {
+	dir, ok := h.getDir()
+	if !ok {
+		return constants.ErrNetworkNotDetected
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d.jsonl", cycle))
+	_, err := os.Stat(path)
+	isNew := os.IsNotExist(err)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	if isNew {
+		h.prune(cycle - constants.MISS_HISTORY_CYCLES)
+	}
+	return nil
+}
+
+func (h *missHistory) listCycles() []int64 {
+	dir, ok := h.getDir()
+	if !ok {
+		return []int64{}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []int64{}
+	}
+	cycles := []int64{}
+	for _, entry := range entries {
+		cycle, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), ".jsonl"), 10, 64)
+		if err == nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	slices.Sort(cycles)
+	return cycles
+}
+
+func (h *missHistory) prune(oldest int64) {
+	for _, cycle := range h.listCycles() {
+		if cycle >= oldest {
+			continue
+		}
+		path, _ := h.getFilePath(cycle) // listed cycles are of the active chain
+		if err := os.Remove(path); err != nil {
+			slog.Warn("failed to remove miss history", "cycle", cycle, "error", err.Error())
+		}
+	}
+}
+
+// load reads records of the cycle, latest record of each level and baker wins
+func (h *missHistory) load(cycle int64) ([]RealizedRights, error) {
+	path, ok := h.getFilePath(cycle)
+	if !ok {
+		return []RealizedRights{}, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RealizedRights{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	type key struct {
+		level int64
+		baker string
+	}
+	latest := map[key]RealizedRights{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record RealizedRights
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // partially written line
+		}
+		latest[key{record.Level, record.Baker}] = record
+	}
+	records := make([]RealizedRights, 0, len(latest))
+	for _, record := range latest {
+		records = append(records, record)
+	}
+	slices.SortFunc(records, func(a, b RealizedRights) int {
+		if a.Level != b.Level {
+			return int(b.Level - a.Level)
+		}
+		return strings.Compare(a.Baker, b.Baker)
+	})
+	return records, scanner.Err()
+}
+
+// getMissContext collects circumstances of the miss at the level, only consensus operations for the level are considered -
+// preattestations included in the block of the level and attestations included in the next block
+func getMissContext(ctx context.Context, level int64, missedBlock bool, missedAttestation bool, baker string, environment missEnvironment) *MissContext {
+	miss := &MissContext{
+		Block:               missedBlock,
+		Attestation:         missedAttestation,
+		SignerServicesDown:  environment.signerServicesDown,
+		DisconnectedLedgers: environment.disconnectedLedgers,
+	}
+	if block, ok := common.GetHistoryBlock(level); ok {
+		miss.Round = block.Round
+		miss.Proposer = block.Proposer
+		miss.PayloadProducer = block.PayloadProducer
+		miss.ArrivalDelays = block.ArrivalDelays
+		for _, delay := range block.ArrivalDelays {
+			arrivedAt := block.Timestamp.Add(time.Duration(delay) * time.Millisecond)
+			if miss.HeadArrivedAt == nil || arrivedAt.Before(*miss.HeadArrivedAt) {
+				miss.HeadArrivedAt = &arrivedAt
+			}
+		}
+	}
+	if missedAttestation {
+		preattestations, err := getConsensusOperations(ctx, level, level)
+		if err != nil {
+			slog.Debug("failed to get consensus operations", "level", level, "error", err.Error())
+			return miss
+		}
+		attestations, err := getConsensusOperations(ctx, level+1, level)
+		switch {
+		case err != nil:
+			slog.Debug("failed to get consensus operations", "level", level+1, "error", err.Error())
+		case slices.Contains(preattestations["preattestation"], baker), slices.Contains(attestations["attestation"], baker):
+			miss.AttestationStatus = LateAttestation
+		default:
+			miss.AttestationStatus = MissingAttestation
+		}
+	}
+	return miss
+}
+
+func (h *missHistory) record(ctx context.Context, observation missObservation) {
+	byCycle := map[int64][]RealizedRights{}
+	for _, right := range observation.rights {
+		if !right.RealizedChecked {
+			continue
+		}
+		// rights of the previous level are reported at the level, see getBlockRightsFor
+		level := right.Level - 1
+		cycle, _, ok := common.GetCycleOfLevel(level)
+		if !ok {
+			continue
+		}
+		for baker, rights := range right.Rights {
+			h.mtx.Lock()
+			recorded, ok := h.recorded[level][baker]
+			h.mtx.Unlock()
+			if ok && slices.Equal(recorded, rights) {
+				continue
+			}
+
+			record := RealizedRights{
+				Level:     level,
+				Baker:     baker,
+				Rights:    slices.Clone(rights),
+				CheckedAt: time.Now(),
+			}
+			if missedBlock, missedAttestation := isMiss(rights); missedBlock || missedAttestation {
+				record.Miss = getMissContext(ctx, level, missedBlock, missedAttestation, baker, observation.environment)
+				slog.Warn("missed rights", "baker", baker, "level", level, "block", missedBlock, "attestation", missedAttestation)
+			}
+			byCycle[cycle] = append(byCycle[cycle], record)
+		}
+	}
+
+	for cycle, records := range byCycle {
+		if err := h.append(cycle, records); err != nil {
+			slog.Warn("failed to persist realized rights", "cycle", cycle, "error", err.Error())
+			continue
+		}
+		h.mtx.Lock()
+		for _, record := range records {
+			if _, ok := h.recorded[record.Level]; !ok {
+				h.recorded[record.Level] = map[string][]int{}
+			}
+			h.recorded[record.Level][record.Baker] = record.Rights
+		}
+		h.mtx.Unlock()
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for level := range h.recorded {
+		if level < observation.headLevel-constants.MISS_HISTORY_RECORDED_LEVELS {
+			delete(h.recorded, level)
+		}
+	}
+}
+
+func (h *missHistory) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case observation := <-h.observations:
+			h.record(ctx, observation)
+		}
+	}
+}
+
+func (h *missHistory) registerEndpoint(app *fiber.Group) {
+	app.Get("/tezbake/misses", func(c *fiber.Ctx) error {
+		baker := c.Query("baker")
+		limit := c.QueryInt("limit", constants.DEFAULT_MISS_HISTORY_LIMIT)
+		if limit <= 0 {
+			return c.Status(400).SendString("invalid limit")
+		}
+		cycles := h.listCycles()
+		if c.Query("cycle") != "" {
+			cycle := c.QueryInt("cycle", -1)
+			if cycle < 0 {
+				return c.Status(400).SendString("invalid cycle")
+			}
+			cycles = []int64{int64(cycle)}
+		}
+
+		misses := []RealizedRights{}
+		for i := len(cycles) - 1; i >= 0 && len(misses) < limit; i-- {
+			records, err := h.load(cycles[i])
+			if err != nil {
+				slog.Warn("failed to load miss history", "cycle", cycles[i], "error", err.Error())
+				return c.Status(500).SendString("failed to load miss history")
+			}
+			for _, record := range records {
+				if record.Miss == nil || (baker != "" && record.Baker != baker) {
+					continue
+				}
+				misses = append(misses, record)
+				if len(misses) >= limit {
+					break
+				}
+			}
+		}
+		return c.JSON(misses)
+	})
+}
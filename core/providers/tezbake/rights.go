@@ -1,298 +1,480 @@
-package tezbake
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"log/slog"
-	"slices"
-
-	"github.com/samber/lo"
-	"github.com/tez-capital/tezpeak/core/common"
-	"github.com/trilitech/tzgo/rpc"
-	"github.com/trilitech/tzgo/tezos"
-)
-
-type BlockRights struct {
-	Level           int64            `json:"level"`
-	Rights          map[string][]int `json:"rights"`
-	RealizedChecked bool             `json:"realized_checked"`
-}
-
-type RightsStatus struct {
-	Level  int64         `json:"level"`
-	Rights []BlockRights `json:"rights"`
-}
-
-func (s *RightsStatus) Clone() RightsStatus {
-	return RightsStatus{
-		Level:  s.Level,
-		Rights: slices.Clone(s.Rights),
-	}
-}
-
-type RightsStatusUpdate struct {
-	RightsStatus
-}
-
-func (s *RightsStatusUpdate) GetId() string {
-	return "rights"
-}
-
-func (s *RightsStatusUpdate) GetData() any {
-	return s.RightsStatus
-}
-
-func initRights(bakers []string) (map[string]int, map[string]int) {
-	attestations := map[string]int{}
-	baking := map[string]int{}
-	for _, baker := range bakers {
-		attestations[baker] = 0
-		baking[baker] = 0
-	}
-	return baking, attestations
-}
-
-type blockBakingRights []struct {
-	Level        int64  `json:"level"`
-	Delegate     string `json:"delegate"`
-	Round        int64  `json:"round"`
-	ConsensusKey string `json:"consensus_key"`
-}
-
-// [{"level":5026842,"delegates":[{"delegate":"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM","first_slot":2608,"attestation_power":1,"consensus_key":"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM"}]}]
-type blockAttestationRights struct {
-	Level     int64 `json:"level"`
-	Delegates []struct {
-		Delegate         string `json:"delegate"`
-		FirstSlot        int64  `json:"first_slot"`
-		AttestationPower int    `json:"attesting_power"`
-		ConsensusKey     string `json:"consensus_key"`
-	} `json:"delegates"`
-}
-
-func attemptWithRightsRpcClients[T any](ctx context.Context, f func(client *common.ActiveRpcNode) (T, error)) (T, error) {
-	return common.AttemptWithRpcClients(ctx, func(client *common.ActiveRpcNode) (T, error) {
-		var result T
-
-		if !client.IsRightsProvider {
-			return result, errors.New("not a rights provider")
-		}
-		return f(client)
-	})
-}
-
-func getBlockRights(ctx context.Context, block int64) (blockBakingRights, blockAttestationRights, error) {
-	bakingRights := blockBakingRights{}
-	attestationRights := blockAttestationRights{}
-	var bakingRightsErr, attestationRightsErr error
-	bakingRightsChan := make(chan struct{})
-	attestationRightsChan := make(chan struct{})
-
-	go func() {
-		url := fmt.Sprintf("chains/main/blocks/head/helpers/baking_rights?all=true&max_priority=1&level=%d", block)
-		bakingRights, bakingRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (blockBakingRights, error) {
-			bakingRights := blockBakingRights{}
-			err := client.Get(ctx, url, &bakingRights)
-			return bakingRights, err
-		})
-		bakingRightsChan <- struct{}{}
-	}()
-
-	go func() {
-		url := fmt.Sprintf("chains/main/blocks/head/helpers/attestation_rights?all=true&max_priority=1&level=%d", block)
-		attestationRights, attestationRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (blockAttestationRights, error) {
-			attestationRights := make([]blockAttestationRights, 0)
-			err := client.Get(ctx, url, &attestationRights)
-			result := blockAttestationRights{Level: block}
-			if len(attestationRights) > 0 {
-				result = attestationRights[0]
-			}
-			return result, err
-		})
-		attestationRightsChan <- struct{}{}
-	}()
-	<-bakingRightsChan
-	<-attestationRightsChan
-	return bakingRights, attestationRights, errors.Join(attestationRightsErr, bakingRightsErr)
-}
-
-func getBlockRightsFor(ctx context.Context, block int64, bakers []string) (BlockRights, error) {
-	relevantBakingRights, relevantAttestationRights := initRights(bakers)
-
-	bakingRights, attestationRights, err := getBlockRights(ctx, block-1)
-	for _, right := range bakingRights {
-		if right.Round > 0 {
-			continue
-		}
-		if _, ok := relevantBakingRights[right.Delegate]; !ok {
-			continue
-		}
-		relevantBakingRights[right.Delegate]++
-	}
-
-	for _, right := range attestationRights.Delegates {
-		if _, ok := relevantAttestationRights[right.Delegate]; !ok {
-			continue
-		}
-		relevantAttestationRights[right.Delegate] += right.AttestationPower
-	}
-
-	if err != nil {
-		slog.Warn("Reported error while getting block rights", "error", err.Error())
-	}
-
-	rights := map[string][]int{}
-	for _, baker := range bakers {
-		rights[baker] = []int{relevantBakingRights[baker], relevantAttestationRights[baker]}
-	}
-
-	return BlockRights{
-		Level:  block,
-		Rights: rights,
-	}, nil
-}
-
-func checkRealized(ctx context.Context, rights BlockRights) (BlockRights, error) {
-	if rights.RealizedChecked {
-		return rights, nil
-	}
-
-	hasAnyRights := false
-	for _, r := range rights.Rights {
-		if len(r) > 1 && (r[0] > 0 || r[1] > 0) {
-			hasAnyRights = true
-			break
-		}
-	}
-	if !hasAnyRights {
-		rights.RealizedChecked = true
-		return rights, nil
-	}
-
-	header, err := attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (*rpc.BlockHeader, error) {
-		return client.GetBlockHeader(ctx, rpc.BlockLevel(rights.Level))
-	})
-
-	if err != nil {
-		return rights, err
-	}
-
-	ops, err := attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) ([][]rpc.Operation, error) {
-		ops, err := client.GetBlockOperations(ctx, rpc.BlockLevel(rights.Level))
-		return ops, err
-	})
-	if err != nil {
-		return rights, err
-	}
-
-	validAttestations := lo.Reduce(ops, func(acc []string, g []rpc.Operation, _ int) []string {
-		for _, tx := range g {
-			for _, c := range tx.Contents {
-				switch c.Kind() {
-				case tezos.OpTypeAttestation, tezos.OpTypeAttestationWithDal:
-					acc = append(acc, c.Meta().Delegate.String())
-				case tezos.OpTypeAttestationsAggregate:
-					op := c.(*rpc.AttestationsAggregate)
-					for _, committee := range op.Metadata.CommitteeMetadata {
-						acc = append(acc, committee.Delegate.String())
-					}
-				}
-			}
-		}
-		return acc
-	}, []string{})
-
-	for baker, r := range rights.Rights {
-		if len(r) > 1 {
-			blockRights, attestationRights := r[0], r[1]
-			bakedBlock := 0
-			if blockRights > 0 && header.PayloadRound == 0 {
-				bakedBlock = 1
-			}
-
-			attestedBlock := 0
-			if slices.Contains(validAttestations, baker) {
-				attestedBlock = 1
-			}
-
-			rights.Rights[baker] = []int{blockRights, attestationRights, bakedBlock, attestedBlock}
-		}
-	}
-	rights.RealizedChecked = true
-
-	return rights, nil
-}
-
-func startRightsStatusProviders(ctx context.Context, bakers []string, blockWindow int64, statusChannel chan<- common.StatusUpdate) {
-	blockChannelId, blockChannel, err := common.SubscribeToBlockHeaderEvents()
-	if err != nil {
-		slog.Error("failed to subscribe to block events", "error", err.Error())
-		return
-	}
-
-	go func() {
-		defer func() {
-			common.UnsubscribeFromBlockHeaderEvents(blockChannelId)
-		}()
-
-		status := RightsStatus{
-			Level:  0,
-			Rights: []BlockRights{},
-		}
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case block, ok := <-blockChannel:
-				if !ok {
-					// levelChannel is closed, exit the loop
-					return
-				}
-
-				if ctx.Done() != nil {
-					return
-				}
-
-				if status.Level >= block.Level {
-					continue
-				}
-
-				// get slice of levels to query
-				minLevel := max(0, block.Level-blockWindow/2+1)
-				maxLevel := block.Level + blockWindow/2 + 1
-				newRights := []BlockRights{}
-				lastCachedLevel := int64(0)
-				for _, right := range status.Rights {
-					if right.Level < minLevel || right.Level > maxLevel {
-						continue
-					}
-					newRights = append(newRights, right)
-					lastCachedLevel = right.Level
-				}
-
-				for i := max(lastCachedLevel+1, minLevel); i < maxLevel; i++ {
-					rights, err := getBlockRightsFor(ctx, i, bakers)
-					if err != nil {
-						slog.Error("failed to get block rights", "error", err.Error())
-						continue
-					}
-					newRights = append(newRights, rights)
-				}
-
-				for i, right := range newRights {
-					if right.Level > block.Level { // we do not check future rights
-						break
-					}
-					newRights[i], _ = checkRealized(ctx, right)
-				}
-
-				status.Level = block.Level
-				status.Rights = newRights
-				statusChannel <- &RightsStatusUpdate{status}
-			}
-		}
-	}()
-
-}
+package tezbake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+	"github.com/trilitech/tzgo/rpc"
+)
+
+// BlockRights are rights of the level before Level, their block is checked for baking
+// and the block at Level for attestations
+type BlockRights struct {
+	Level int64 `json:"level"`
+	// baker -> [round 0 blocks, attestations, baked, attested]
+	Rights          map[string][]int `json:"rights"`
+	RealizedChecked bool             `json:"realized_checked"`
+	// baker -> rights beyond round 0 and preattestations
+	Details map[string]*BakerBlockRights `json:"details,omitempty"`
+}
+
+type BakerBlockRights struct {
+	// rounds the baker can bake the block at, up to the configured max round
+	Rounds []int64 `json:"rounds"`
+	// payload round of the block if it matches one of the baker rounds
+	BakedRound   *int64 `json:"baked_round,omitempty"`
+	Attestations int    `json:"attestations"`
+	Attested     bool   `json:"attested"`
+	// nil when the block does not include preattestations, they are included only in blocks baked at round > 0
+	Preattested *bool `json:"preattested,omitempty"`
+}
+
+type NextRight struct {
+	Level int64  `json:"level"`
+	Kind  string `json:"kind"` // block or attestation
+	// estimated time of the level, missing until protocol constants are known
+	Eta *time.Time `json:"eta,omitempty"`
+}
+
+type RightsStatus struct {
+	Level  int64         `json:"level"`
+	Rights []BlockRights `json:"rights"`
+	// next right of each baker within the rights window
+	Next map[string]NextRight `json:"next,omitempty"`
+}
+
+func (s *RightsStatus) Clone() RightsStatus {
+	return RightsStatus{
+		Level:  s.Level,
+		Rights: slices.Clone(s.Rights),
+		Next:   maps.Clone(s.Next),
+	}
+}
+
+// getNextRights finds first future right of each baker and estimates its time from the head block
+func getNextRights(rights []BlockRights, headLevel int64, headTimestamp time.Time) map[string]NextRight {
+	result := map[string]NextRight{}
+	for _, right := range rights {
+		if right.Level <= headLevel {
+			continue
+		}
+		for baker, r := range right.Rights {
+			if _, ok := result[baker]; ok || len(r) < 2 {
+				continue
+			}
+			kind := ""
+			switch {
+			case r[0] > 0:
+				kind = "block"
+			case r[1] > 0:
+				kind = "attestation"
+			default:
+				continue
+			}
+			next := NextRight{Level: right.Level, Kind: kind}
+			if eta, ok := common.EstimateLevelTime(headLevel, headTimestamp, right.Level); ok {
+				next.Eta = &eta
+			}
+			result[baker] = next
+		}
+	}
+	return result
+}
+
+type RightsStatusUpdate struct {
+	RightsStatus
+}
+
+func (s *RightsStatusUpdate) GetId() string {
+	return "rights"
+}
+
+func (s *RightsStatusUpdate) GetData() any {
+	return s.RightsStatus
+}
+
+func initRights(bakers []string) (map[string]int, map[string]int) {
+	attestations := map[string]int{}
+	baking := map[string]int{}
+	for _, baker := range bakers {
+		attestations[baker] = 0
+		baking[baker] = 0
+	}
+	return baking, attestations
+}
+
+type blockBakingRights []struct {
+	Level        int64  `json:"level"`
+	Delegate     string `json:"delegate"`
+	Round        int64  `json:"round"`
+	ConsensusKey string `json:"consensus_key"`
+}
+
+// [{"level":5026842,"delegates":[{"delegate":"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM","first_slot":2608,"attestation_power":1,"consensus_key":"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM"}]}]
+type blockAttestationRights struct {
+	Level     int64 `json:"level"`
+	Delegates []struct {
+		Delegate         string `json:"delegate"`
+		FirstSlot        int64  `json:"first_slot"`
+		AttestationPower int    `json:"attesting_power"`
+		ConsensusKey     string `json:"consensus_key"`
+	} `json:"delegates"`
+}
+
+// consensusOperations are delegates of consensus operations for a level included in a block by operation kind
+type consensusOperations map[string][]string
+
+type blockConsensusOperation struct {
+	Contents []struct {
+		Kind  string `json:"kind"`
+		Level int64  `json:"level"`
+		// aggregates carry level in consensus content
+		ConsensusContent struct {
+			Level int64 `json:"level"`
+		} `json:"consensus_content"`
+		Metadata struct {
+			Delegate  string `json:"delegate"`
+			Committee []struct {
+				Delegate string `json:"delegate"`
+			} `json:"committee"`
+		} `json:"metadata"`
+	} `json:"contents"`
+}
+
+// getConsensusOperations returns delegates of (pre)attestations for the level included in the block, aggregates are flattened
+func getConsensusOperations(ctx context.Context, block int64, level int64) (consensusOperations, error) {
+	operations, err := attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) ([]blockConsensusOperation, error) {
+		operations := []blockConsensusOperation{}
+		err := client.Get(ctx, fmt.Sprintf("chains/main/blocks/%d/operations/0", block), &operations)
+		return operations, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := consensusOperations{}
+	for _, operation := range operations {
+		for _, content := range operation.Contents {
+			if content.Level != level && content.ConsensusContent.Level != level {
+				continue
+			}
+			kind := strings.TrimSuffix(strings.TrimSuffix(content.Kind, "_with_dal"), "s_aggregate")
+			if content.Metadata.Delegate != "" {
+				result[kind] = append(result[kind], content.Metadata.Delegate)
+			}
+			for _, member := range content.Metadata.Committee {
+				result[kind] = append(result[kind], member.Delegate)
+			}
+		}
+	}
+	return result, nil
+}
+
+func attemptWithRightsRpcClients[T any](ctx context.Context, f func(client *common.ActiveRpcNode) (T, error)) (T, error) {
+	return common.AttemptWithRpcClients(ctx, func(client *common.ActiveRpcNode) (T, error) {
+		var result T
+
+		if !client.IsRightsProvider || !client.Allows(common.RightsRpcCapability) {
+			return result, fmt.Errorf("%w: not a rights provider", constants.ErrNodeNotApplicable)
+		}
+		return f(client)
+	})
+}
+
+func getBlockRights(ctx context.Context, block int64, maxRound int64) (blockBakingRights, blockAttestationRights, error) {
+	bakingRights := blockBakingRights{}
+	attestationRights := blockAttestationRights{}
+	var bakingRightsErr, attestationRightsErr error
+	bakingRightsChan := make(chan struct{})
+	attestationRightsChan := make(chan struct{})
+
+	go func() {
+		url := fmt.Sprintf("chains/main/blocks/head/helpers/baking_rights?all=true&max_round=%d&level=%d", maxRound, block)
+		bakingRights, bakingRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (blockBakingRights, error) {
+			bakingRights := blockBakingRights{}
+			err := client.Get(ctx, url, &bakingRights)
+			return bakingRights, err
+		})
+		bakingRightsChan <- struct{}{}
+	}()
+
+	go func() {
+		url := fmt.Sprintf("chains/main/blocks/head/helpers/attestation_rights?all=true&max_priority=1&level=%d", block)
+		attestationRights, attestationRightsErr = attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (blockAttestationRights, error) {
+			attestationRights := make([]blockAttestationRights, 0)
+			err := client.Get(ctx, url, &attestationRights)
+			result := blockAttestationRights{Level: block}
+			if len(attestationRights) > 0 {
+				result = attestationRights[0]
+			}
+			return result, err
+		})
+		attestationRightsChan <- struct{}{}
+	}()
+	<-bakingRightsChan
+	<-attestationRightsChan
+	return bakingRights, attestationRights, errors.Join(attestationRightsErr, bakingRightsErr)
+}
+
+// newBlockRights builds rights of the bakers from their baking rounds and attestation power
+func newBlockRights(block int64, bakers []string, rounds map[string][]int64, attestations map[string]int) BlockRights {
+	rights := map[string][]int{}
+	details := map[string]*BakerBlockRights{}
+	for _, baker := range bakers {
+		bakerRounds := slices.Clone(rounds[baker])
+		slices.Sort(bakerRounds)
+		blocks := 0
+		if slices.Contains(bakerRounds, 0) {
+			blocks = 1
+		}
+		rights[baker] = []int{blocks, attestations[baker]}
+		details[baker] = &BakerBlockRights{
+			Rounds:       bakerRounds,
+			Attestations: attestations[baker],
+		}
+	}
+	return BlockRights{
+		Level:   block,
+		Rights:  rights,
+		Details: details,
+	}
+}
+
+func getBlockRightsFor(ctx context.Context, block int64, bakers []string, maxRound int64) (BlockRights, error) {
+	relevantRounds := map[string][]int64{}
+	_, relevantAttestationRights := initRights(bakers)
+
+	bakingRights, attestationRights, err := getBlockRights(ctx, block-1, maxRound)
+	for _, right := range bakingRights {
+		if right.Round > maxRound || !slices.Contains(bakers, right.Delegate) {
+			continue
+		}
+		relevantRounds[right.Delegate] = append(relevantRounds[right.Delegate], right.Round)
+	}
+
+	for _, right := range attestationRights.Delegates {
+		if _, ok := relevantAttestationRights[right.Delegate]; !ok {
+			continue
+		}
+		relevantAttestationRights[right.Delegate] += right.AttestationPower
+	}
+
+	if err != nil {
+		slog.Warn("Reported error while getting block rights", "error", err.Error())
+	}
+
+	return newBlockRights(block, bakers, relevantRounds, relevantAttestationRights), nil
+}
+
+func checkRealized(ctx context.Context, rights BlockRights) (BlockRights, error) {
+	if rights.RealizedChecked {
+		return rights, nil
+	}
+
+	hasAnyRights, hasAttestationRights := false, false
+	for baker, r := range rights.Rights {
+		if len(r) > 1 && (r[0] > 0 || r[1] > 0) {
+			hasAnyRights = true
+		}
+		if len(r) > 1 && r[1] > 0 {
+			hasAttestationRights = true
+		}
+		if details, ok := rights.Details[baker]; ok && len(details.Rounds) > 0 {
+			hasAnyRights = true
+		}
+	}
+	if !hasAnyRights {
+		rights.RealizedChecked = true
+		return rights, nil
+	}
+
+	// rights of the previous level are reported at the level, see getBlockRightsFor
+	level := rights.Level - 1
+	header, err := attemptWithRightsRpcClients(ctx, func(client *common.ActiveRpcNode) (*rpc.BlockHeader, error) {
+		return client.GetBlockHeader(ctx, rpc.BlockLevel(level))
+	})
+
+	if err != nil {
+		return rights, err
+	}
+
+	// attestations of the level are included in the next block
+	operations, err := getConsensusOperations(ctx, rights.Level, level)
+	if err != nil {
+		return rights, err
+	}
+	validAttestations := operations["attestation"]
+
+	// preattestations of the level are included in the block of the level if it was baked at round > 0
+	var validPreattestations []string
+	if hasAttestationRights {
+		previousOperations, err := getConsensusOperations(ctx, level, level)
+		if err != nil {
+			return rights, err
+		}
+		validPreattestations = previousOperations["preattestation"]
+	}
+
+	details := map[string]*BakerBlockRights{}
+	for baker, r := range rights.Rights {
+		if len(r) > 1 {
+			blockRights, attestationRights := r[0], r[1]
+			// payload of the round 0 block comes from the baker
+			bakedBlock := 0
+			if blockRights > 0 && header.PayloadRound == 0 {
+				bakedBlock = 1
+			}
+
+			attestedBlock := 0
+			if slices.Contains(validAttestations, baker) {
+				attestedBlock = 1
+			}
+
+			rights.Rights[baker] = []int{blockRights, attestationRights, bakedBlock, attestedBlock}
+		}
+
+		if previous, ok := rights.Details[baker]; ok {
+			bakerDetails := *previous
+			bakerDetails.BakedRound = nil
+			bakerDetails.Preattested = nil
+			payloadRound := int64(header.PayloadRound)
+			if slices.Contains(bakerDetails.Rounds, payloadRound) {
+				bakerDetails.BakedRound = &payloadRound
+			}
+			bakerDetails.Attested = slices.Contains(validAttestations, baker)
+			if len(validPreattestations) > 0 && bakerDetails.Attestations > 0 {
+				preattested := slices.Contains(validPreattestations, baker)
+				bakerDetails.Preattested = &preattested
+			}
+			details[baker] = &bakerDetails
+		}
+	}
+	if len(details) > 0 {
+		rights.Details = details
+	}
+	rights.RealizedChecked = true
+
+	return rights, nil
+}
+
+func startRightsStatusProviders(ctx context.Context, bakers []string, blockWindow int64, maxRound int64, rightsCache *cycleRightsCache, statusChannel chan<- common.StatusUpdate) {
+	blockChannelId, blockChannel, err := common.SubscribeToBlockHeaderEvents()
+	if err != nil {
+		slog.Error("failed to subscribe to block events", "error", err.Error())
+		return
+	}
+
+	reorgChannelId, reorgChannel, err := common.SubscribeToReorgEvents()
+	if err != nil {
+		common.UnsubscribeFromBlockHeaderEvents(blockChannelId)
+		slog.Error("failed to subscribe to reorg events", "error", err.Error())
+		return
+	}
+
+	go func() {
+		defer func() {
+			common.UnsubscribeFromBlockHeaderEvents(blockChannelId)
+			common.UnsubscribeFromReorgEvents(reorgChannelId)
+		}()
+
+		status := RightsStatus{
+			Level:  0,
+			Rights: []BlockRights{},
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case block, ok := <-blockChannel:
+				if !ok {
+					// levelChannel is closed, exit the loop
+					return
+				}
+
+				if ctx.Done() != nil {
+					return
+				}
+
+				if status.Level >= block.Level {
+					continue
+				}
+
+				// get slice of levels to query
+				minLevel := max(0, block.Level-blockWindow/2+1)
+				maxLevel := block.Level + blockWindow/2 + 1
+				newRights := []BlockRights{}
+				lastCachedLevel := int64(0)
+				for _, right := range status.Rights {
+					if right.Level < minLevel || right.Level > maxLevel {
+						continue
+					}
+					newRights = append(newRights, right)
+					lastCachedLevel = right.Level
+				}
+
+				for i := max(lastCachedLevel+1, minLevel); i < maxLevel; i++ {
+					rights, err := rightsCache.getBlockRights(ctx, i, bakers)
+					if err != nil {
+						// cycle not known yet or its rights not available, fall back to the level rights
+						slog.Debug("failed to get block rights from cycle rights", "level", i, "error", err.Error())
+						rights, err = getBlockRightsFor(ctx, i, bakers, maxRound)
+					}
+					if err != nil {
+						slog.Error("failed to get block rights", "error", err.Error())
+						continue
+					}
+					newRights = append(newRights, rights)
+				}
+
+				for i, right := range newRights {
+					if right.Level > block.Level { // we do not check future rights
+						break
+					}
+					newRights[i], _ = checkRealized(ctx, right)
+				}
+
+				rightsCache.recordRealized(newRights)
+				status.Level = block.Level
+				status.Rights = newRights
+				status.Next = getNextRights(newRights, block.Level, block.Timestamp)
+				statusChannel <- &RightsStatusUpdate{status}
+			case reorg, ok := <-reorgChannel:
+				if !ok {
+					return
+				}
+
+				// realization of replaced blocks has to be checked again
+				recheck := false
+				status.Rights = slices.Clone(status.Rights)
+				for i, right := range status.Rights {
+					if right.Level < reorg.Level || right.Level > status.Level || !right.RealizedChecked {
+						continue
+					}
+					right.Rights = maps.Clone(right.Rights)
+					right.RealizedChecked = false
+					checked, err := checkRealized(ctx, right)
+					status.Rights[i] = checked
+					rightsCache.recordRealized([]BlockRights{checked})
+					if err != nil {
+						slog.Warn("failed to recheck realized rights after reorg", "level", right.Level, "error", err.Error())
+					}
+					recheck = true
+				}
+				if recheck {
+					slog.Debug("rights rechecked after reorg", "level", reorg.Level)
+					statusChannel <- &RightsStatusUpdate{status}
+				}
+			}
+		}
+	}()
+
+}
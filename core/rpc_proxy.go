@@ -0,0 +1,46 @@
+package core
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core/common"
+)
+
+func registerRpcProxyEndpoint(app *fiber.Group, config *configuration.Runtime) {
+	isAllowed := func() bool {
+		return config.Mode == configuration.PrivatePeakMode || config.RpcProxy.Public
+	}
+
+	app.Get("/rpc/*", func(c *fiber.Ctx) error {
+		if !isAllowed() {
+			return c.Status(403).SendString("not allowed")
+		}
+
+		rpcPath := c.Params("*")
+		if !config.RpcProxy.Allows(rpcPath) {
+			return c.Status(403).SendString("rpc path not allowed")
+		}
+
+		response, err := common.ProxyRpc(c.Context(), rpcPath, string(c.Request().URI().QueryString()), config.RpcProxy.MaxResponseSize)
+		switch {
+		case errors.Is(err, constants.ErrRpcResponseTooLarge):
+			return c.Status(fiber.StatusBadGateway).SendString(err.Error())
+		case err != nil:
+			slog.Debug("failed to proxy rpc", "path", rpcPath, "error", err.Error())
+			return c.Status(fiber.StatusBadGateway).SendString("no node answered")
+		}
+
+		if response.ContentType != "" {
+			c.Set(fiber.HeaderContentType, response.ContentType)
+		}
+		c.Set("X-Tezpeak-Node", response.Node)
+		if response.Cached {
+			c.Set("X-Tezpeak-Cache", "hit")
+		}
+		return c.Status(response.StatusCode).Send(response.Body)
+	})
+}
@@ -1,100 +1,177 @@
-package main
-
-import (
-	"context"
-	"embed"
-	"flag"
-	"fmt"
-	"log/slog"
-	"net/http"
-	"os"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/filesystem"
-
-	"github.com/tez-capital/tezpeak/configuration"
-	"github.com/tez-capital/tezpeak/constants"
-	"github.com/tez-capital/tezpeak/core"
-	"github.com/tez-capital/tezpeak/util"
-)
-
-type Message struct {
-	Text string
-}
-
-//go:embed web/dist/*
-var staticFiles embed.FS
-
-type staticFs struct {
-	http.FileSystem
-}
-
-func (c staticFs) Open(name string) (http.File, error) {
-	// try to open .html file first, because filesystem middleware forbids to open folders
-	// but we want to provide html if it exists when user tries to access path like /tezpay
-	f, err := c.FileSystem.Open(name + ".html")
-	if err != nil {
-		return c.FileSystem.Open(name)
-	}
-	return f, nil
-}
-
-func main() {
-	logLevelFlag := flag.String("log-level", "info", "Log level")
-	versionFlag := flag.Bool("version", false, "Print version and exit")
-	rootDirFlag := flag.String("root-dir", "", "Root directory (relevant only if auto detecting configuration)")
-	autodetectConfigurationFlag := flag.String("autodetect-configuration", "", "Path to file where to save autodetected configuration")
-	flag.Parse()
-
-	if autodetectConfigurationFlag != nil && *autodetectConfigurationFlag != "" {
-		rootDir := "."
-		if rootDirFlag != nil && *rootDirFlag != "" {
-			rootDir = *rootDirFlag
-		}
-
-		slog.Info("Autodetecting configuration", "rootDir", rootDir, "autodetectConfiguration", *autodetectConfigurationFlag)
-		configuration.AutoDetect(rootDir, *autodetectConfigurationFlag)
-		os.Exit(0)
-	}
-
-	if *versionFlag {
-		fmt.Printf("tezpeak %s - %s \n", constants.TEZPEAK_VERSION, constants.TEZPEAK_CODENAME)
-		return
-	}
-
-	util.InitLog(*logLevelFlag)
-	config, err := configuration.Load()
-	if err != nil {
-		panic(err)
-	}
-
-	// // 11695267
-	// // tz1hZvgjekGo7DmQjWh7XnY5eLQD8wNYPczE
-	// common.StartNodeStatusProviders(context.Background(), config.Nodes, nil)
-	// fmt.Println(tezbake.GetBlockRightsFor(context.Background(), 11695742, []string{"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM"}))
-	// os.Exit(0)
-
-	app := fiber.New()
-	group, ok := app.Group("/api").(*fiber.Group)
-	if !ok {
-		panic("failed to create api group")
-	}
-
-	err = core.Run(context.Background(), config, group)
-	if err != nil {
-		panic(err)
-	}
-
-	app.Use("/", filesystem.New(filesystem.Config{
-		Root:         staticFs{http.FS(staticFiles)},
-		Index:        "index.html",
-		NotFoundFile: "/web/dist/index.html",
-		PathPrefix:   "/web/dist",
-		Browse:       false,
-	}))
-
-	err = app.Listen(config.Listen)
-	if err != nil && err != http.ErrServerClosed {
-		panic(err)
-	}
-}
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+
+	"github.com/tez-capital/tezpeak/configuration"
+	"github.com/tez-capital/tezpeak/constants"
+	"github.com/tez-capital/tezpeak/core"
+	"github.com/tez-capital/tezpeak/util"
+)
+
+type Message struct {
+	Text string
+}
+
+//go:embed web/dist/*
+var staticFiles embed.FS
+
+type staticFs struct {
+	http.FileSystem
+}
+
+func (c staticFs) Open(name string) (http.File, error) {
+	// try to open .html file first, because filesystem middleware forbids to open folders
+	// but we want to provide html if it exists when user tries to access path like /tezpay
+	f, err := c.FileSystem.Open(name + ".html")
+	if err != nil {
+		return c.FileSystem.Open(name)
+	}
+	return f, nil
+}
+
+func main() {
+	logLevelFlag := flag.String("log-level", "info", "Log level (overrides log_level)")
+	versionFlag := flag.Bool("version", false, "Print version and exit")
+	rootDirFlag := flag.String("root-dir", "", "Root directory (relevant only if auto detecting configuration)")
+	autodetectConfigurationFlag := flag.String("autodetect-configuration", "", "Path to file where to save autodetected configuration")
+	autodetectInteractiveFlag := flag.Bool("autodetect-interactive", false, "Confirm each autodetected app, baker and node (relevant only if auto detecting configuration)")
+	configFileFlag := flag.String("config", "", "Path to configuration file (overrides TEZPEAK_CONFIG_FILE)")
+	printEffectiveConfigFlag := flag.Bool("print-effective-config", false, "Print effective configuration with the source of each value and exit")
+	// flags overriding top level configuration keys, nested keys are handled by --<key.path>=<value>
+	configurationFlags := map[string]string{
+		"id":         "id",
+		"listen":     "listen",
+		"mode":       "mode",
+		"app-root":   "app_root",
+		"log-level":  "log_level",
+		"log-format": "log_format",
+		"log-file":   "log_file",
+	}
+	flag.String("id", "", "Id to show in the header (overrides id)")
+	flag.String("listen", "", "Address to listen on (overrides listen)")
+	flag.String("mode", "", "Mode to operate in - auto, public or private (overrides mode)")
+	flag.String("app-root", "", "Path to the root where apps are located (overrides app_root)")
+	flag.String("log-format", "", "Log format - text or json (overrides log_format)")
+	flag.String("log-file", "", "Path to log file, logs to stdout if not set (overrides log_file)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "  --<key.path>=<value>\n    \tOverride any configuration key, e.g. --modules.tezbake.bakers=tz1...,tz1...\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Commands:\n  config validate\n    \tValidate configuration and exit\n  doctor\n    \tCheck nodes, bakers, signer, apps and wallets and exit\n")
+	}
+
+	args, configurationOverrides := configuration.ExtractOverrideArgs(os.Args[1:])
+	flag.CommandLine.Parse(args)
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := configurationFlags[f.Name]; ok {
+			configurationOverrides = append(configurationOverrides, configuration.ConfigurationOverride{
+				Key:    key,
+				Value:  f.Value.String(),
+				Origin: "--" + f.Name,
+			})
+		}
+	})
+
+	if autodetectConfigurationFlag != nil && *autodetectConfigurationFlag != "" {
+		rootDir := "."
+		if rootDirFlag != nil && *rootDirFlag != "" {
+			rootDir = *rootDirFlag
+		}
+
+		slog.Info("Autodetecting configuration", "rootDir", rootDir, "autodetectConfiguration", *autodetectConfigurationFlag)
+		configuration.AutoDetect(rootDir, *autodetectConfigurationFlag, configuration.AutoDetectOptions{
+			Interactive: *autodetectInteractiveFlag,
+			Input:       os.Stdin,
+			Output:      os.Stdout,
+		})
+		os.Exit(0)
+	}
+
+	if *versionFlag {
+		fmt.Printf("tezpeak %s - %s \n", constants.TEZPEAK_VERSION, constants.TEZPEAK_CODENAME)
+		return
+	}
+
+	util.InitLog(*logLevelFlag)
+	loadOptions := configuration.LoadOptions{
+		ConfigFile: *configFileFlag,
+		Overrides:  configurationOverrides,
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "config":
+		if flag.Arg(1) != "validate" {
+			fmt.Fprintf(os.Stderr, "unknown config command %q, expected validate\n", flag.Arg(1))
+			os.Exit(1)
+		}
+		runConfigValidate(loadOptions)
+		return
+	case "doctor":
+		runDoctor(loadOptions)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config, effectiveConfig, err := configuration.LoadWithOptions(loadOptions)
+	if *printEffectiveConfigFlag {
+		if effectiveConfig != nil {
+			fmt.Print(effectiveConfig.String())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err := util.ConfigureLog(config.Log); err != nil {
+		slog.Error("failed to configure log", "error", err.Error())
+	}
+
+	// // 11695267
+	// // tz1hZvgjekGo7DmQjWh7XnY5eLQD8wNYPczE
+	// common.StartNodeStatusProviders(context.Background(), config.Nodes, nil)
+	// fmt.Println(tezbake.GetBlockRightsFor(context.Background(), 11695742, []string{"tz1P6WKJu2rcbxKiKRZHKQKmKrpC9TfW1AwM"}))
+	// os.Exit(0)
+
+	app := fiber.New()
+	group, ok := app.Group("/api").(*fiber.Group)
+	if !ok {
+		panic("failed to create api group")
+	}
+
+	err = core.Run(context.Background(), config, group)
+	if err != nil {
+		panic(err)
+	}
+
+	app.Use("/", filesystem.New(filesystem.Config{
+		Root:         staticFs{http.FS(staticFiles)},
+		Index:        "index.html",
+		NotFoundFile: "/web/dist/index.html",
+		PathPrefix:   "/web/dist",
+		Browse:       false,
+	}))
+
+	err = app.Listen(config.Listen)
+	if err != nil && err != http.ErrServerClosed {
+		panic(err)
+	}
+}
@@ -1,15 +1,28 @@
-package util
-
-import (
-	"os"
-	"time"
-)
-
-func CheckFileChangedTime(path string) time.Time {
-	info, err := os.Stat(path)
-	if err != nil {
-		return time.Time{}
-	}
-
-	return info.ModTime()
-}
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func CheckFileChangedTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// WriteFileAtomic replaces the file so readers never see it partially written
+func WriteFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,30 +1,375 @@
-package util
-
-import (
-	"log/slog"
-	"os"
-)
-
-// / ideally move somewhere else where it makes sense
-func InitLog(logLevel string) {
-	l := slog.LevelInfo
-	switch logLevel {
-	case "debug":
-		l = slog.LevelDebug
-	case "info":
-		l = slog.LevelInfo
-	case "warn":
-		l = slog.LevelWarn
-	case "error":
-		l = slog.LevelError
-	default:
-		slog.Error("invalid log level")
-		os.Exit(1)
-	}
-
-	// replace next 4 lines with SetLogLoggerLevel in go 1.22
-	textHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: l,
-	})
-	slog.SetDefault(slog.New(textHandler))
-}
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	moduleLogAttribute = "module"
+	modulePathPrefix   = "github.com/tez-capital/tezpeak/"
+
+	TextLogFormat = "text"
+	JsonLogFormat = "json"
+)
+
+type LogOptions struct {
+	Level string
+	// text or json
+	Format string
+	// writes to stdout if empty
+	File string
+	// rotate when the file exceeds size in MB, 0 disables
+	FileMaxSize int
+	// rotate when the file is older than days, 0 disables
+	FileMaxAge int
+	// rotated files to keep, 0 keeps all
+	FileMaxBackups int
+	// levels per module, e.g. tezbake/rights: debug
+	ModuleLevels map[string]string
+}
+
+type logLevels struct {
+	mtx     sync.RWMutex
+	level   slog.Level
+	modules map[string]slog.Level
+}
+
+var (
+	levels = &logLevels{
+		level:   slog.LevelInfo,
+		modules: map[string]slog.Level{},
+	}
+	logOutput io.Closer
+)
+
+func ParseLogLevel(logLevel string) (slog.Level, error) {
+	switch strings.ToLower(logLevel) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", logLevel)
+	}
+}
+
+func formatLogLevel(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// minLevel is the lowest level any module logs at
+func (l *logLevels) minLevel() slog.Level {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	result := l.level
+	for _, level := range l.modules {
+		result = min(result, level)
+	}
+	return result
+}
+
+// moduleLevel looks up level of the module (e.g. tezbake/rights), then of its parent (tezbake)
+func (l *logLevels) moduleLevel(module string) slog.Level {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	for ; module != ""; module = module[:max(strings.LastIndex(module, "/"), 0)] {
+		if level, ok := l.modules[module]; ok {
+			return level
+		}
+	}
+	return l.level
+}
+
+// moduleFromPC derives module from the source of the log record,
+// e.g. core/providers/tezbake/rights.go logs as tezbake/rights
+func moduleFromPC(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := frame.Function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	} else if j := strings.Index(pkg, "."); j >= 0 {
+		pkg = pkg[:j]
+	}
+	pkg = strings.TrimPrefix(pkg, modulePathPrefix)
+	pkg = strings.TrimPrefix(pkg, "core/providers/")
+	pkg = strings.TrimPrefix(pkg, "core/")
+	return pkg + "/" + strings.TrimSuffix(filepath.Base(frame.File), ".go")
+}
+
+// moduleLevelHandler filters records by level of the module they come from and adds module attribute
+type moduleLevelHandler struct {
+	handler slog.Handler
+	// set through slog.With("module", ...), otherwise derived from the source
+	module string
+}
+
+func (h *moduleLevelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= levels.minLevel()
+}
+
+func (h *moduleLevelHandler) Handle(ctx context.Context, record slog.Record) error {
+	module := h.module
+	if module == "" {
+		module = moduleFromPC(record.PC)
+		record.AddAttrs(slog.String(moduleLogAttribute, module))
+	}
+	if record.Level < levels.moduleLevel(module) {
+		return nil
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *moduleLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	module := h.module
+	for _, attr := range attrs {
+		if attr.Key == moduleLogAttribute {
+			module = attr.Value.String()
+		}
+	}
+	return &moduleLevelHandler{handler: h.handler.WithAttrs(attrs), module: module}
+}
+
+func (h *moduleLevelHandler) WithGroup(name string) slog.Handler {
+	return &moduleLevelHandler{handler: h.handler.WithGroup(name), module: h.module}
+}
+
+// / ideally move somewhere else where it makes sense
+func InitLog(logLevel string) {
+	if err := ConfigureLog(LogOptions{Level: logLevel}); err != nil {
+		slog.Warn("failed to configure log, using defaults", "error", err.Error())
+	}
+}
+
+// ConfigureLog replaces default logger based on options, invalid levels fall back to info
+func ConfigureLog(options LogOptions) error {
+	var output io.Writer = os.Stdout
+	var closer io.Closer
+	if options.File != "" {
+		file, err := newRotatingFile(options.File, options.FileMaxSize, options.FileMaxAge, options.FileMaxBackups)
+		if err != nil {
+			return err
+		}
+		output, closer = file, file
+	}
+
+	handlerOptions := &slog.HandlerOptions{
+		Level: slog.LevelDebug, // filtered by moduleLevelHandler
+	}
+	var handler slog.Handler
+	switch strings.ToLower(options.Format) {
+	case JsonLogFormat:
+		handler = slog.NewJSONHandler(output, handlerOptions)
+	case TextLogFormat, "":
+		handler = slog.NewTextHandler(output, handlerOptions)
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return fmt.Errorf("invalid log format %q", options.Format)
+	}
+
+	errs := []error{}
+	level, err := ParseLogLevel(options.Level)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	modules := map[string]slog.Level{}
+	for module, moduleLevel := range options.ModuleLevels {
+		parsed, err := ParseLogLevel(moduleLevel)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("module %s: %w", module, err))
+			continue
+		}
+		modules[module] = parsed
+	}
+
+	levels.mtx.Lock()
+	levels.level = level
+	levels.modules = modules
+	levels.mtx.Unlock()
+
+	slog.SetDefault(slog.New(&moduleLevelHandler{handler: handler}))
+	if logOutput != nil {
+		logOutput.Close()
+	}
+	logOutput = closer
+
+	for _, err := range errs {
+		slog.Warn("invalid log level, using info", "error", err.Error())
+	}
+	return nil
+}
+
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+func GetLogLevels() LogLevels {
+	levels.mtx.RLock()
+	defer levels.mtx.RUnlock()
+	result := LogLevels{
+		Level:   formatLogLevel(levels.level),
+		Modules: map[string]string{},
+	}
+	for module, level := range levels.modules {
+		result.Modules[module] = formatLogLevel(level)
+	}
+	return result
+}
+
+// SetLogLevel changes level of the module at runtime, empty module changes the default level
+// and empty level of a module resets it to the default level
+func SetLogLevel(module string, logLevel string) error {
+	if module != "" && logLevel == "" {
+		levels.mtx.Lock()
+		defer levels.mtx.Unlock()
+		delete(levels.modules, module)
+		return nil
+	}
+
+	level, err := ParseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	levels.mtx.Lock()
+	defer levels.mtx.Unlock()
+	if module == "" {
+		levels.level = level
+		return nil
+	}
+	levels.modules[module] = level
+	return nil
+}
+
+type rotatingFile struct {
+	mtx        sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newRotatingFile(path string, maxSizeMB int, maxAgeDays int, maxBackups int) (*rotatingFile, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = info.ModTime()
+	if f.size == 0 {
+		f.openedAt = time.Now()
+	}
+	return nil
+}
+
+func (f *rotatingFile) shouldRotate(size int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.maxSize > 0 && f.size+int64(size) > f.maxSize {
+		return true
+	}
+	return f.maxAge > 0 && time.Since(f.openedAt) > f.maxAge
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	backup := fmt.Sprintf("%s.%s", f.path, time.Now().UTC().Format("20060102T150405.000"))
+	if err := os.Rename(f.path, backup); err != nil {
+		return errors.Join(err, f.open())
+	}
+	f.removeOldBackups()
+	return f.open()
+}
+
+// removeOldBackups keeps at most maxBackups rotated files
+func (f *rotatingFile) removeOldBackups() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil || len(backups) <= f.maxBackups {
+		return
+	}
+	// timestamp suffix sorts chronologically, oldest first
+	for _, backup := range backups[:len(backups)-f.maxBackups] {
+		os.Remove(backup)
+	}
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %s\n", err.Error())
+			if f.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,7 @@
+import { derived, type Readable } from "svelte/store"
+import { state as globalState } from "."
+import type { PeakStatus } from "@src/common/types/status"
+
+export const state = derived(globalState, $state => {
+	return $state?.modules.host
+}) as Readable<PeakStatus["modules"]["host"]>
@@ -11,6 +11,11 @@ export const APP_ID = derived(state, $state => {
 	const subId = $state?.id
 	return subId ? `TEZPEAK - ${subId}` : "TEZPEAK"
 })
+// network is shown only when not on mainnet so testnet dashboards are visually distinct
+export const APP_NETWORK = derived(state, $state => {
+	const network = $state?.network
+	return network && network !== "mainnet" ? network : undefined
+})
 
 const provider = new StatusProvider("/api/sse")
 document.onvisibilitychange = () => {
@@ -31,6 +36,8 @@ provider.onstatuschange = (status) => {
 	APP_CONNECTION_STATUS.set(status)
 }
 
+export const mempool = derived(state, $state => $state?.mempool)
+
 export const nodes = derived(state, $state => {
 	if ($state === undefined) {
 		return []
@@ -13,8 +13,13 @@ export type NodeStatus = {
 	connection_status: "connected" | "disconnected" | "connecting" | "paused"
 	block?: {
 		hash: string
+		predecessor?: string
 		timestamp: string
 		//fitness: string
+		round?: number
+		payload_producer?: string
+		proposer?: string
+		received_at?: string
 		level_info: {
 			level: number
 			level_position: number
@@ -32,8 +37,100 @@ export type NodeStatus = {
 			current_inflow: number
 			current_outflow: number
 		} | null
+		bandwidth?: {
+			inbound: number
+			outbound: number
+		}
+		peers?: {
+			incoming: number
+			outgoing: number
+			private: number
+			public: number
+			top_peers: Array<{
+				peer_id: string
+				address?: string
+				incoming: boolean
+				total_sent: number
+				total_recv: number
+			}>
+			greylisted_points: number
+			greylisted_ips: number
+		}
+		min_peers: number
+		low_peer_count: boolean
+		updated_at?: string
 	} | null
 	is_essential: boolean
+	pool?: NodePoolStatus
+	consensus?: NodeConsensusStatus
+	version?: string
+	commit?: string
+	network_version?: string
+	protocol?: string
+	next_protocol?: string
+	history_mode?: string
+	upcoming_protocol?: string
+	supports_upcoming_protocol?: boolean
+	version_checked_at?: string
+	rpc?: { [category in RpcCategory]?: RpcCategoryStats }
+	error?: string
+	sync?: NodeSyncStatus
+	capabilities?: { [capability in RpcCapability]?: boolean }
+	roles?: Array<NodeRole>
+}
+
+export type RpcCapability = "monitor" | "rights" | "raw_context" | "votes" | "network" | "mempool"
+
+export type NodeRole = "block" | "rights" | "governance" | "network_info" | "mempool"
+
+export type RpcCategory = "rights" | "context" | "blocks" | "monitor" | "other"
+
+export type RpcCategoryStats = {
+	requests: number
+	errors: number
+	rejected: number
+	avg_latency: number
+	p95_latency: number
+}
+
+export type NodeConsensusStatus = {
+	lag_levels: number
+	lag_seconds: number
+	on_canonical_branch: boolean
+}
+
+export type ConsensusStatus = {
+	network_head?: {
+		level: number
+		hash: string
+		timestamp: string
+	}
+	forks?: Array<{
+		level: number
+		branches: { [hash: string]: Array<string> }
+	}>
+}
+
+export type SyncState = "synced" | "unsynced" | "stuck"
+
+export type NodeSyncStatus = {
+	state: SyncState
+	since: string
+	last_head_at: string
+	transitions: Array<{ state: SyncState, at: string }>
+}
+
+export type NodePoolStatus = {
+	synced: boolean
+	sync_state?: SyncState
+	head_level: number
+	latency: number
+	error_rate: number
+	score: number
+	circuit: "closed" | "open" | "half_open"
+	retry_at?: string
+	last_error?: string
+	checked_at?: string
 }
 
 export type NodesStatus = { [key: string]: NodeStatus }
@@ -42,11 +139,27 @@ export type BlockRights = {
 	level: number
 	rights: { [key: string]: Array<number> }
 	realized_checked: boolean
+	details?: { [key: string]: BakerBlockRights }
+}
+
+export type BakerBlockRights = {
+	rounds: Array<number>
+	baked_round?: number
+	attestations: number
+	attested: boolean
+	preattested?: boolean
+}
+
+export type NextRight = {
+	level: number
+	kind: "block" | "attestation"
+	eta?: string
 }
 
 export type RightsStatus = {
 	level: number
 	rights: Array<BlockRights>
+	next?: { [key: string]: NextRight }
 	realized_checked?: boolean
 }
 
@@ -88,8 +201,78 @@ export type LedgerWalletStatus = {
 	pkh: string
 }
 
+export type RightsSummary = {
+	total: number
+	passed: number
+	realized: number
+	missed: number
+	unchecked: number
+	participation?: number
+}
+
+export type CycleRights = {
+	cycle: number
+	first_level: number
+	last_level: number
+	bakers: { [key: string]: { blocks: RightsSummary, attestations: RightsSummary } }
+}
+
+export type CycleRightsStatus = {
+	level: number
+	cycles: Array<CycleRights>
+}
+
+export type UpcomingRight = {
+	level: number
+	eta: string
+}
+
+export type MaintenanceWindow = {
+	from_level: number
+	to_level: number
+	from: string
+	to: string
+	duration: number
+}
+
+export type BakerMaintenanceStatus = {
+	next_baking?: UpcomingRight
+	next_attestation?: UpcomingRight
+	longest_free_window?: MaintenanceWindow
+}
+
+export type MaintenanceStatus = {
+	level: number
+	horizon: number
+	bakers: { [key: string]: BakerMaintenanceStatus }
+	longest_free_window?: MaintenanceWindow
+}
+
+export type MissContext = {
+	block: boolean
+	attestation: boolean
+	round: number
+	proposer?: string
+	payload_producer?: string
+	arrival_delays?: { [key: string]: number }
+	head_arrived_at?: string
+	attestation_status?: "late" | "missing"
+	signer_services_down?: Array<string>
+	disconnected_ledgers?: Array<string>
+}
+
+export type RealizedRights = {
+	level: number
+	baker: string
+	rights: Array<number>
+	checked_at: string
+	miss?: MissContext
+}
+
 export type TezbakeStatus = {
 	rights: RightsStatus,
+	cycle_rights?: CycleRightsStatus,
+	maintenance?: MaintenanceStatus,
 	services: ServicesStatus,
 	bakers: BakersStatus,
 	wallets: { [key: string]: LedgerWalletStatus },
@@ -106,13 +289,83 @@ export type TezpayStatus = {
 	wallet: WalletStatus
 }
 
+export type HostResourceLevel = "ok" | "warning" | "error"
+
+export type HostDiskStatus = {
+	path: string
+	total: number
+	available: number
+	used_percent: number
+	inodes_total: number
+	inodes_free: number
+	inodes_used_percent: number
+	level: HostResourceLevel
+}
+
+export type HostStatus = {
+	load?: {
+		load1: number
+		load5: number
+		load15: number
+		cpus: number
+		level: HostResourceLevel
+	}
+	memory?: {
+		total: number
+		available: number
+		used_percent: number
+		swap_total: number
+		swap_free: number
+		swap_used_percent: number
+		level: HostResourceLevel
+	}
+	disks: { [key: string]: HostDiskStatus }
+	clock?: {
+		offset: number
+		level: HostResourceLevel
+	}
+	level: HostResourceLevel
+	timestamp: number
+}
+
+export type MempoolOperation = {
+	hash: string
+	kind: string
+	source: string
+	class: "applied" | "branch_delayed" | "refused"
+	error?: string
+	seen_at: string
+}
+
+export type MempoolStatus = {
+	counters: { [key: string]: number }
+	recent: Array<MempoolOperation>
+	refused: Array<MempoolOperation>
+}
+
+export type CycleStatus = {
+	cycle: number
+	first_level: number
+	last_level: number
+	level: number
+	progress: number
+	remaining_blocks: number
+	eta: string
+}
+
 export type PeakStatus = {
 	id?: string
+	network?: string
+	chain_id?: string
 	modules: {
 		"tezbake": TezbakeStatus | undefined
 		"tezpay": TezpayStatus | undefined
+		"host"?: HostStatus
 	}
 	nodes: NodesStatus
+	consensus?: ConsensusStatus
+	mempool?: MempoolStatus
+	cycle?: CycleStatus
 }
 
 export type StatusUpdate = {
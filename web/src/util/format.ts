@@ -67,4 +67,24 @@ export function formatBalance(mutez: string | bigint | number) {
 
 export function formatPercentage(percentage: number | string) {
   return `${Number(percentage).toFixed(2)}%`
+}
+
+export function formatBytes(bytes: number) {
+  const units = ["B", "kB", "MB", "GB", "TB"]
+  let unit = 0
+  while (bytes >= 1000 && unit < units.length - 1) {
+    bytes /= 1000
+    unit++
+  }
+  return `${bytes.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`
+}
+
+export function formatBandwidth(bytesPerSecond: number) {
+  const units = ["B/s", "kB/s", "MB/s", "GB/s"]
+  let unit = 0
+  while (bytesPerSecond >= 1000 && unit < units.length - 1) {
+    bytesPerSecond /= 1000
+    unit++
+  }
+  return `${bytesPerSecond.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`
 }
\ No newline at end of file